@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/hitesh22rana/sourcecollector/pkg/cache"
+
+	"github.com/spf13/cobra"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the cache of processed files",
+}
+
+var cachePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove stale entries from the cache",
+	Long: `Remove stale entries from the cache.
+Removes the entries of files which changed or no longer exist, the processed files no entry refers to, and with --max-age everything not used for longer than the given duration.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		cacheDir, _ := cmd.Flags().GetString("cache-dir")
+		maxAge, _ := cmd.Flags().GetDuration("max-age")
+
+		c, err := cache.Open(cacheDir)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		removed, err := c.Prune(maxAge)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		fmt.Printf("🧹 Removed %d cache file(s) from %s\n", removed, cacheDir)
+	},
+}
+
+func init() {
+	cachePruneCmd.Flags().String("cache-dir", "", "Cache directory")
+	cachePruneCmd.Flags().Duration("max-age", 0, "Also remove everything not used for longer than this duration (e.g. 168h), 0 keeps it")
+	cachePruneCmd.MarkFlagRequired("cache-dir")
+
+	cacheCmd.AddCommand(cachePruneCmd)
+	rootCmd.AddCommand(cacheCmd)
+}
@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+
+	sourcecollector "github.com/hitesh22rana/sourcecollector/pkg"
+
+	"github.com/spf13/cobra"
+)
+
+var explainCmd = &cobra.Command{
+	Use:   "explain <path>",
+	Short: "Explain why a path is excluded from the collection",
+	Long: `Explain why a path is excluded from the collection.
+Shows the rule and its source (.gitignore line, extension allowlist, unwanted files and folders, --include/--exclude, --symlinks) which excluded the path or one of its parent directories.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		input, _ := cmd.Flags().GetString("input")
+
+		sc, err := sourcecollector.NewSourceCollector(input, "", false)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// Explain the selection the collection with the same flags would make
+		if err := configureSelection(cmd, sc); err != nil {
+			log.Fatal(err)
+		}
+
+		skipped, err := sc.Explain(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if skipped == nil {
+			fmt.Printf("✅ %s is collected\n", args[0])
+			return
+		}
+
+		fmt.Printf("❌ %s is excluded\n   path:   %s\n   rule:   %s\n   source: %s\n   detail: %s\n", args[0], skipped.Path, skipped.Rule, skipped.Source, skipped.Detail)
+	},
+}
+
+func init() {
+	explainCmd.Flags().StringP("input", "i", ".", "Input directory path")
+	addSelectionFlags(explainCmd.Flags())
+	rootCmd.AddCommand(explainCmd)
+}
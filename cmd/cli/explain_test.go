@@ -0,0 +1,21 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExplainSelectionFlags(t *testing.T) {
+	input := secretsFixture(t)
+
+	stdout, code := runCommand(t, "explain", "-i", input, "config.go")
+	if code != 0 || !strings.Contains(string(stdout), "✅ config.go is collected") {
+		t.Errorf("explain exited with %d:\n%s", code, stdout)
+	}
+
+	// The file excluded by the flags of the collection is explained as excluded
+	stdout, code = runCommand(t, "explain", "-i", input, "--exclude", "config.go", "config.go")
+	if code != 0 || !strings.Contains(string(stdout), "❌ config.go is excluded") || !strings.Contains(string(stdout), "rule:   exclude-pattern") {
+		t.Errorf("explain --exclude exited with %d:\n%s", code, stdout)
+	}
+}
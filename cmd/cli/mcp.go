@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	sourcecollector "github.com/hitesh22rana/sourcecollector/pkg"
+	"github.com/hitesh22rana/sourcecollector/pkg/mcp"
+
+	"github.com/spf13/cobra"
+)
+
+var mcpCmd = &cobra.Command{
+	Use:   "mcp",
+	Short: "Run a Model Context Protocol server over stdio",
+	Long: `Run a Model Context Protocol server over stdio, so AI agents can explore the input directory.
+The server offers the tools list_tree, read_files, collect (with a token budget) and search, which only see the files the collection would include.
+Nothing but the protocol messages is written to stdout.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		input, _ := cmd.Flags().GetString("input")
+
+		// Serve until stdin is closed or interrupted
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		// Fail early on invalid flags, rather than on every tool call
+		newCollector := func() (*sourcecollector.SourceCollector, error) {
+			sc, err := sourcecollector.NewSourceCollector(input, "", false)
+			if err != nil {
+				return nil, err
+			}
+
+			if err := configureCollector(cmd, sc); err != nil {
+				return nil, err
+			}
+
+			return sc, nil
+		}
+
+		if _, err := newCollector(); err != nil {
+			log.Fatal(err)
+		}
+
+		server := &mcp.Server{
+			Name:         "sourcecollector",
+			Version:      rootCmd.Version,
+			NewCollector: newCollector,
+		}
+
+		if err := server.Serve(ctx, os.Stdin, os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	addProcessingFlags(mcpCmd.Flags())
+	mcpCmd.Flags().StringP("input", "i", ".", "Input directory path")
+	rootCmd.AddCommand(mcpCmd)
+}
@@ -0,0 +1,197 @@
+package cli
+
+import (
+	"fmt"
+
+	sourcecollector "github.com/hitesh22rana/sourcecollector/pkg"
+	"github.com/hitesh22rana/sourcecollector/pkg/cache"
+	"github.com/hitesh22rana/sourcecollector/pkg/secrets"
+	"github.com/hitesh22rana/sourcecollector/pkg/validators"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// addCollectorFlags adds the flags shared by all the commands which write a collection
+func addCollectorFlags(flags *pflag.FlagSet) {
+	flags.StringP("input", "i", "", "Input directory path")
+	flags.StringP("output", "o", "output.txt", "Output file path")
+	flags.Bool("fast", false, "Faster result but may result in unordered data, default(false)")
+	addProcessingFlags(flags)
+}
+
+// addProcessingFlags adds the flags which decide how the files of a collection are selected and processed
+func addProcessingFlags(flags *pflag.FlagSet) {
+	addSelectionFlags(flags)
+	flags.String("format", string(sourcecollector.FormatText), "Format of the output: text or json")
+	addTreeFlags(flags)
+	flags.String("max-file-size", "", "Maximum size of a single file in bytes (e.g. 64KB, 1MB), lines (e.g. 2000lines) or tokens (e.g. 8000tokens)")
+	flags.String("oversize-policy", string(sourcecollector.OversizeSkip), "What to do with files over --max-file-size: skip, head or head-tail")
+	flags.String("on-error", string(sourcecollector.ErrorPolicySkip), "What to do when a file cannot be read: skip it and continue, or fail the collection")
+	flags.Bool("redact", false, "Redact secrets (keys, tokens, passwords) before writing the output, default(false)")
+	flags.StringArray("secret-pattern", nil, "Additional secret regex to redact, optionally named as kind=regex (implies --redact)")
+	flags.String("secrets-allowlist", "", "File with accepted secret fingerprints, one per line")
+	flags.Bool("line-numbers", false, "Prefix every line of the source code with its line number, default(false)")
+	flags.Bool("outline", false, "Only collect the declarations and signatures of the Go, Python, TypeScript/JavaScript, Java and Kotlin files, without the bodies, default(false)")
+	flags.Int("max-line-length", 0, "Truncate lines longer than this many characters, 0 means unlimited, default(0)")
+	flags.String("cache-dir", "", "Cache the processed files in this directory, so repeat runs only re-read changed files")
+	flags.Bool("dedupe", false, "Write the files with the same content as an earlier file as a reference to it, default(false)")
+}
+
+// addSelectionFlags adds the flags which narrow down the collected files
+func addSelectionFlags(flags *pflag.FlagSet) {
+	flags.StringArray("include", nil, "Only collect the files matching this glob, relative to the input directory (e.g. **/*.go), can be repeated")
+	flags.StringArray("exclude", nil, "Skip the paths matching this glob, relative to the input directory (e.g. docs/**), can be repeated")
+	flags.String("symlinks", string(sourcecollector.SymlinkFollowWithinRoot), "How to handle symbolic links: skip, follow or follow-within-root (only links pointing inside the input directory), use follow to also collect the links pointing outside like earlier versions")
+}
+
+// addTreeFlags adds the flags which decide how the tree structure is rendered
+func addTreeFlags(flags *pflag.FlagSet) {
+	flags.String("tree-order", string(sourcecollector.TreeOrderAlphabetical), "Order of the entries of a directory in the tree, after the directories: alpha or natural (file2 before file10)")
+	flags.StringSlice("tree-annotate", nil, "Annotate the tree entries with their size, lines and/or tokens, directories show the totals of their files (e.g. size,tokens)")
+	flags.Bool("tree-ascii", false, "Draw the tree with ASCII characters only, default(false)")
+}
+
+// newCollectorFromFlags makes a SourceCollector configured by the flags added with addCollectorFlags
+func newCollectorFromFlags(cmd *cobra.Command) (*sourcecollector.SourceCollector, error) {
+	input, _ := cmd.Flags().GetString("input")
+	output, _ := cmd.Flags().GetString("output")
+	fast, _ := cmd.Flags().GetBool("fast")
+
+	sc, err := sourcecollector.NewSourceCollector(input, output, fast)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := configureCollector(cmd, sc); err != nil {
+		return nil, err
+	}
+
+	return sc, nil
+}
+
+// configureCollector sets the options of the collector from the flags added with addProcessingFlags
+func configureCollector(cmd *cobra.Command, sc *sourcecollector.SourceCollector) error {
+	format, _ := cmd.Flags().GetString("format")
+	maxFileSize, _ := cmd.Flags().GetString("max-file-size")
+	oversizePolicy, _ := cmd.Flags().GetString("oversize-policy")
+	onError, _ := cmd.Flags().GetString("on-error")
+	redact, _ := cmd.Flags().GetBool("redact")
+	secretPatterns, _ := cmd.Flags().GetStringArray("secret-pattern")
+	lineNumbers, _ := cmd.Flags().GetBool("line-numbers")
+	maxLineLength, _ := cmd.Flags().GetInt("max-line-length")
+	cacheDir, _ := cmd.Flags().GetString("cache-dir")
+	dedupe, _ := cmd.Flags().GetBool("dedupe")
+	outline, _ := cmd.Flags().GetBool("outline")
+
+	var err error
+	sc.Format, err = sourcecollector.ParseFormat(format)
+	if err != nil {
+		return err
+	}
+
+	if err := configureSelection(cmd, sc); err != nil {
+		return err
+	}
+
+	if err := configureTree(cmd, sc); err != nil {
+		return err
+	}
+
+	sc.LineNumbers = lineNumbers
+	sc.MaxLineLength = maxLineLength
+	sc.Dedupe = dedupe
+
+	// The line numbers of an outline would not match the lines of the file
+	if outline && lineNumbers {
+		return fmt.Errorf("--outline cannot be combined with --line-numbers")
+	}
+	sc.Outline = outline
+
+	switch policy := sourcecollector.ErrorPolicy(onError); policy {
+	case sourcecollector.ErrorPolicySkip, sourcecollector.ErrorPolicyFail:
+		sc.OnError = policy
+	default:
+		return fmt.Errorf("invalid --on-error %q, expected skip or fail", onError)
+	}
+
+	if maxFileSize != "" {
+		sc.SizeLimit, err = sourcecollector.ParseSizeLimit(maxFileSize, sourcecollector.OversizePolicy(oversizePolicy))
+		if err != nil {
+			return err
+		}
+	}
+
+	if redact || len(secretPatterns) > 0 {
+		sc.Redactor, err = newRedactor(cmd)
+		if err != nil {
+			return err
+		}
+	}
+
+	if cacheDir != "" {
+		sc.Cache, err = cache.Open(cacheDir)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// configureSelection narrows down the files of the collector by the flags added with addSelectionFlags
+func configureSelection(cmd *cobra.Command, sc *sourcecollector.SourceCollector) error {
+	include, _ := cmd.Flags().GetStringArray("include")
+	exclude, _ := cmd.Flags().GetStringArray("exclude")
+	symlinks, _ := cmd.Flags().GetString("symlinks")
+
+	var err error
+	sc.Symlinks, err = sourcecollector.ParseSymlinkPolicy(symlinks)
+	if err != nil {
+		return err
+	}
+
+	if len(include) == 0 && len(exclude) == 0 {
+		return nil
+	}
+
+	sc.Validator, err = validators.NewPatternValidator(sc.Validator, sc.Input, include, exclude)
+	return err
+}
+
+// configureTree sets the tree options of the collector from the flags added with addTreeFlags
+func configureTree(cmd *cobra.Command, sc *sourcecollector.SourceCollector) error {
+	treeOrder, _ := cmd.Flags().GetString("tree-order")
+	treeAnnotate, _ := cmd.Flags().GetStringSlice("tree-annotate")
+	treeASCII, _ := cmd.Flags().GetBool("tree-ascii")
+
+	var err error
+	sc.Tree.ASCII = treeASCII
+	sc.Tree.Order, err = sourcecollector.ParseTreeOrder(treeOrder)
+	if err != nil {
+		return err
+	}
+
+	sc.Tree.Annotations, err = sourcecollector.ParseTreeAnnotations(treeAnnotate)
+	return err
+}
+
+// newRedactor makes a Redactor configured by the secret flags
+func newRedactor(cmd *cobra.Command) (*secrets.Redactor, error) {
+	secretPatterns, _ := cmd.Flags().GetStringArray("secret-pattern")
+	secretsAllowlist, _ := cmd.Flags().GetString("secrets-allowlist")
+
+	redactor, err := secrets.NewRedactor(secretPatterns)
+	if err != nil {
+		return nil, err
+	}
+
+	if secretsAllowlist != "" {
+		redactor.Allowlist, err = secrets.LoadAllowlist(secretsAllowlist)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return redactor, nil
+}
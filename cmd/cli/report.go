@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	sourcecollector "github.com/hitesh22rana/sourcecollector/pkg"
+	"github.com/hitesh22rana/sourcecollector/pkg/secrets"
+)
+
+// exitCodeSecretsFound is the exit code used when --fail-on-secrets finds a secret
+const exitCodeSecretsFound = 3
+
+// failOnFindings prints the findings as JSON to stdout and exits with exitCodeSecretsFound
+func failOnFindings(findings []secrets.Finding) {
+	data, err := json.MarshalIndent(map[string]any{"findings": findings}, "", "  ")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	fmt.Println(string(data))
+	fmt.Fprintf(os.Stderr, "found %d secret(s), output was not written\n", len(findings))
+	os.Exit(exitCodeSecretsFound)
+}
+
+// reportRedactions prints what was redacted and where, and optionally saves the findings as JSON
+func reportRedactions(findings []secrets.Finding, reportPath string) error {
+	if reportPath != "" {
+		if err := writeJSON(reportPath, findings); err != nil {
+			return err
+		}
+	}
+
+	if len(findings) == 0 {
+		return nil
+	}
+
+	fmt.Printf("\n🔒 Redacted %d secret(s):\n", len(findings))
+	for _, finding := range findings {
+		fmt.Printf("   %s:%d:%d\t%s\n", finding.Path, finding.Line, finding.Column, finding.Kind)
+	}
+
+	return nil
+}
+
+// writeJSON writes the value as indented JSON to the path
+func writeJSON(path string, value any) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// reportFailedFiles prints the files which could not be collected
+func reportFailedFiles(collectionErr *sourcecollector.CollectionError) {
+	fmt.Fprintf(os.Stderr, "\n⚠️  Failed to collect %d file(s):\n", len(collectionErr.Errors))
+	for _, fileErr := range collectionErr.Errors {
+		fmt.Fprintf(os.Stderr, "   %s\t%v\n", fileErr.Path, fileErr.Err)
+	}
+}
+
+// printStats prints the files which would be collected, largest first, followed by the totals
+func printStats(stats *sourcecollector.Stats) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "BYTES\tLINES\tTOKENS\t\tPATH")
+	for _, file := range stats.Files {
+		path := file.Path
+		if file.SizeStatus != "" {
+			path += " [" + string(file.SizeStatus) + "]"
+		}
+		fmt.Fprintf(w, "%d\t%d\t%d\t\t%s\n", file.Bytes, file.Lines, file.Tokens, path)
+	}
+	fmt.Fprintf(w, "%d\t%d\t%d\t\tTOTAL (%d files)\n", stats.TotalBytes, stats.TotalLines, stats.TotalTokens, len(stats.Files))
+	w.Flush()
+}
@@ -1,9 +1,13 @@
 package cli
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	sourcecollector "github.com/hitesh22rana/sourcecollector/pkg"
@@ -20,37 +24,159 @@ Perfect for feeding your files to AI tools without any fuss.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		startTime := time.Now()
 
-		input, _ := cmd.Flags().GetString("input")
-		output, _ := cmd.Flags().GetString("output")
-		fast, _ := cmd.Flags().GetBool("fast")
+		redactionReport, _ := cmd.Flags().GetString("redaction-report")
+		failOnSecrets, _ := cmd.Flags().GetBool("fail-on-secrets")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		report, _ := cmd.Flags().GetString("report")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		noClobber, _ := cmd.Flags().GetBool("no-clobber")
+		appendOutput, _ := cmd.Flags().GetBool("append")
+		stream, _ := cmd.Flags().GetBool("stream")
+		treePosition, _ := cmd.Flags().GetString("tree-position")
 
-		sc, err := sourcecollector.NewSourceCollector(input, output, fast)
+		// Cancel the collection on interrupt or when the timeout is reached
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		sc, err := newCollectorFromFlags(cmd)
 		if err != nil {
 			log.Fatal(err)
 		}
 
-		sourceTree, err := sc.GenerateSourceTree()
+		switch {
+		case noClobber:
+			sc.WriteMode = sourcecollector.WriteModeNoClobber
+		case appendOutput:
+			// A json collection is a single document, another one cannot be appended to it
+			if sc.Format == sourcecollector.FormatJSON {
+				log.Fatal("--append cannot be combined with --format json")
+			}
+			sc.WriteMode = sourcecollector.WriteModeAppend
+		}
+
+		// Scanning for secrets or reporting them implies redaction
+		if sc.Redactor == nil && (failOnSecrets || redactionReport != "") {
+			sc.Redactor, err = newRedactor(cmd)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		// Stream the files while the input is walked, without building the source tree first
+		if stream {
+			placement, err := sourcecollector.ParseTreePlacement(treePosition)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			err = sc.StreamSourceCode(ctx, placement)
+			finishCollection(sc, err, report, redactionReport, startTime)
+			return
+		}
+
+		sourceTree, err := sc.GenerateSourceTree(ctx)
 		if err != nil {
 			log.Fatal(err)
 		}
 
-		sourcetreeStructure, err := sc.GenerateSourceTreeStructure(sourceTree)
+		if report != "" {
+			if err := writeJSON(report, sc.SkippedPaths()); err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		// Preview the selection without writing the output
+		if dryRun {
+			stats, err := sc.Stats(ctx, sourceTree)
+
+			var collectionErr *sourcecollector.CollectionError
+			if err != nil && !errors.As(err, &collectionErr) {
+				log.Fatal(err)
+			}
+
+			printStats(stats)
+			if collectionErr != nil {
+				reportFailedFiles(collectionErr)
+			}
+			return
+		}
+
+		// In fail closed mode, stop before writing anything if a secret is found
+		if failOnSecrets {
+			// The files which cannot be read are skipped by the collection too, unless the policy stops at the first one
+			findings, err := sc.ScanSecrets(ctx, sourceTree)
+
+			var collectionErr *sourcecollector.CollectionError
+			if err != nil && (!errors.As(err, &collectionErr) || sc.OnError == sourcecollector.ErrorPolicyFail) {
+				log.Fatal(err)
+			}
+
+			if len(findings) > 0 {
+				failOnFindings(findings)
+			}
+		}
+
+		sourcetreeStructure, err := sc.GenerateSourceTreeStructure(ctx, sourceTree)
 		if err != nil {
 			log.Fatal(err)
 		}
 
-		if err := sc.SaveSourceCode(sourceTree, sourcetreeStructure); err != nil {
+		err = sc.SaveSourceCode(ctx, sourceTree, sourcetreeStructure)
+		finishCollection(sc, err, "", redactionReport, startTime)
+	},
+}
+
+// finishCollection reports the outcome of a collection, the files which could not be collected are listed at the end and the run only fails if the policy says so
+func finishCollection(sc *sourcecollector.SourceCollector, err error, report string, redactionReport string, startTime time.Time) {
+	var collectionErr *sourcecollector.CollectionError
+	if err != nil && !errors.As(err, &collectionErr) {
+		log.Fatal(err)
+	}
+
+	// The skipped paths of a streamed collection are only known once it is done
+	if report != "" {
+		if err := writeJSON(report, sc.SkippedPaths()); err != nil {
 			log.Fatal(err)
 		}
+	}
 
-		fmt.Printf("\n┌───────────────────────────────┐\n│ 🕒 Collection Time: %-10s│\n└───────────────────────────────┘\n", time.Since(startTime).Round(time.Millisecond))
-	},
+	if sc.Redactor != nil {
+		if err := reportRedactions(sc.Redactor.Findings(), redactionReport); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	fmt.Printf("\n┌───────────────────────────────┐\n│ 🕒 Collection Time: %-10s│\n└───────────────────────────────┘\n", time.Since(startTime).Round(time.Millisecond))
+
+	if collectionErr != nil {
+		reportFailedFiles(collectionErr)
+		if sc.OnError == sourcecollector.ErrorPolicyFail {
+			os.Exit(1)
+		}
+	}
 }
 
 func init() {
-	rootCmd.Flags().StringP("input", "i", "", "Input directory path")
-	rootCmd.Flags().StringP("output", "o", "output.txt", "Output file path")
-	rootCmd.Flags().Bool("fast", false, "Faster result but may result in unordered data, default(false)")
+	addCollectorFlags(rootCmd.Flags())
+	rootCmd.Flags().Duration("timeout", 0, "Abort the collection after this duration (e.g. 30s, 2m), 0 means no timeout")
+	rootCmd.Flags().Bool("dry-run", false, "List the files which would be collected with their size, lines and estimated tokens, without writing the output")
+	rootCmd.Flags().String("report", "", "Write the skipped paths and the rule which excluded each of them as JSON to this path")
+	rootCmd.Flags().String("redaction-report", "", "Write the redaction report as JSON to this path (implies --redact)")
+	rootCmd.Flags().Bool("fail-on-secrets", false, fmt.Sprintf("Exit with code %d and print the findings as JSON instead of writing the output if a secret is found", exitCodeSecretsFound))
+	rootCmd.Flags().Bool("no-clobber", false, "Fail instead of overwriting an existing output file")
+	rootCmd.Flags().Bool("append", false, "Append to an existing output file instead of overwriting it, not with --format json")
+	rootCmd.Flags().Bool("stream", false, "Write the files while the input is walked, without building the source tree first, for huge inputs. The order of the files varies between runs")
+	rootCmd.Flags().String("tree-position", string(sourcecollector.TreePlacementStart), "Where --stream writes the tree: start (the files are buffered in a temporary file) or end (single pass)")
+	rootCmd.MarkFlagsMutuallyExclusive("no-clobber", "append")
+	rootCmd.MarkFlagsMutuallyExclusive("stream", "dry-run")
+	rootCmd.MarkFlagsMutuallyExclusive("stream", "fail-on-secrets")
+	rootCmd.MarkFlagsMutuallyExclusive("stream", "tree-annotate")
 	rootCmd.MarkFlagRequired("input")
 }
 
@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	sourcecollector "github.com/hitesh22rana/sourcecollector/pkg"
+	"github.com/hitesh22rana/sourcecollector/pkg/server"
+
+	"github.com/spf13/cobra"
+)
+
+// shutdownTimeout is how long the running requests may take to finish on shutdown
+const shutdownTimeout = 10 * time.Second
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve collections of local directories over HTTP",
+	Long: `Serve collections of local directories over HTTP.
+Only the directories under the allowed roots can be collected. The endpoints take the directory as the root parameter:
+
+  GET /collect?root=<dir>&include=<glob>&exclude=<glob>&format=text|json   streams the collection
+  GET /tree?root=<dir>                                                     returns the tree structure
+  GET /stats?root=<dir>                                                    returns the size of every file as JSON
+
+A relative root is relative to the first allowed root. A collection stops as soon as its client disconnects.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		listen, _ := cmd.Flags().GetString("listen")
+		allowedRoots, _ := cmd.Flags().GetStringArray("allow-root")
+		maxConcurrent, _ := cmd.Flags().GetInt("max-concurrent")
+		fast, _ := cmd.Flags().GetBool("fast")
+
+		// Serve until interrupted
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		srv, err := server.NewServer(allowedRoots, maxConcurrent, fast)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// Every request gets its own collector, so the redaction findings are not shared between requests
+		srv.Configure = func(sc *sourcecollector.SourceCollector) error {
+			return configureCollector(cmd, sc)
+		}
+
+		httpServer := &http.Server{
+			Addr:              listen,
+			Handler:           srv.Handler(),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		go func() {
+			<-ctx.Done()
+
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			defer cancel()
+
+			httpServer.Shutdown(shutdownCtx)
+		}()
+
+		fmt.Printf("🌐 Serving %s on http://%s (Ctrl+C to stop)\n", strings.Join(srv.AllowedRoots, ", "), listen)
+
+		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	addProcessingFlags(serveCmd.Flags())
+	serveCmd.Flags().String("listen", "127.0.0.1:8080", "Address to listen on")
+	serveCmd.Flags().StringArray("allow-root", nil, "Directory which can be collected together with its subdirectories, can be repeated")
+	serveCmd.Flags().Int("max-concurrent", 4, "Maximum number of requests processed at the same time, the others wait")
+	serveCmd.Flags().Bool("fast", false, "Read the files of a collection concurrently, the order of the files may vary, default(false)")
+	serveCmd.MarkFlagRequired("allow-root")
+	rootCmd.AddCommand(serveCmd)
+}
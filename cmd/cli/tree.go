@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	sourcecollector "github.com/hitesh22rana/sourcecollector/pkg"
+
+	"github.com/spf13/cobra"
+)
+
+var treeCmd = &cobra.Command{
+	Use:   "tree",
+	Short: "Print the tree structure of the input directory without the file contents",
+	Long: `Print the tree structure of the input directory without the file contents, as a map of the repository.
+The tree only shows the files the collection would include, unless --show-excluded is set.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		input, _ := cmd.Flags().GetString("input")
+		maxDepth, _ := cmd.Flags().GetInt("max-depth")
+		collapse, _ := cmd.Flags().GetInt("collapse")
+		showExcluded, _ := cmd.Flags().GetBool("show-excluded")
+		format, _ := cmd.Flags().GetString("format")
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		treeFormat, err := sourcecollector.ParseTreeFormat(format)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		sc, err := sourcecollector.NewSourceCollector(input, "", true)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if err := configureSelection(cmd, sc); err != nil {
+			log.Fatal(err)
+		}
+
+		if err := configureTree(cmd, sc); err != nil {
+			log.Fatal(err)
+		}
+
+		sc.Tree.MaxDepth = maxDepth
+		sc.Tree.MaxChildren = collapse
+		sc.Tree.ShowExcluded = showExcluded
+
+		sourceTree, err := sc.GenerateSourceTree(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		sourceTreeStructure, err := sc.RenderSourceTree(ctx, sourceTree, treeFormat)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		fmt.Print(sourceTreeStructure)
+	},
+}
+
+func init() {
+	treeCmd.Flags().StringP("input", "i", ".", "Input directory path")
+	addSelectionFlags(treeCmd.Flags())
+	addTreeFlags(treeCmd.Flags())
+	treeCmd.Flags().Int("max-depth", 0, "Only show the entries up to this many levels below the input directory, 0 means unlimited")
+	treeCmd.Flags().Int("collapse", 0, "Only show this many entries of a directory and sum up the rest in one line, 0 means unlimited")
+	treeCmd.Flags().Bool("show-excluded", false, "Also show the entries excluded from the collection, marked with the rule which excluded them")
+	treeCmd.Flags().String("format", string(sourcecollector.TreeFormatText), "Format of the tree: text, json or markdown")
+	rootCmd.AddCommand(treeCmd)
+}
@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	sourcecollector "github.com/hitesh22rana/sourcecollector/pkg"
+
+	"github.com/spf13/cobra"
+)
+
+var unpackCmd = &cobra.Command{
+	Use:   "unpack <bundle>",
+	Short: "Recreate the files of a collected output",
+	Long: `Recreate the files of a collected output, e.g. a version edited by a model, under the target directory.
+The bundle can be in any of the output formats, use - to read it from stdin. Text between the files is ignored.
+Absolute paths and paths leaving the target directory are rejected before anything is written.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		target, _ := cmd.Flags().GetString("target")
+		stripComponents, _ := cmd.Flags().GetInt("strip-components")
+		onConflict, _ := cmd.Flags().GetString("on-conflict")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		policy := sourcecollector.ConflictPolicy(onConflict)
+		switch policy {
+		case sourcecollector.ConflictFail, sourcecollector.ConflictSkip, sourcecollector.ConflictOverwrite:
+		default:
+			log.Fatalf("invalid --on-conflict %q, expected fail, skip or overwrite", onConflict)
+		}
+
+		var (
+			data []byte
+			err  error
+		)
+		if args[0] == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(args[0])
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		files, err := sourcecollector.ParseBundle(data)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		results, err := sourcecollector.Unpack(files, sourcecollector.UnpackOptions{
+			Target:          target,
+			StripComponents: stripComponents,
+			OnConflict:      policy,
+			DryRun:          dryRun,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		printUnpackResults(results, dryRun)
+	},
+}
+
+// printUnpackResults prints what happened to every file, or the diffs of a dry run
+func printUnpackResults(results []sourcecollector.UnpackResult, dryRun bool) {
+	if dryRun {
+		for _, result := range results {
+			fmt.Print(result.Diff)
+		}
+	}
+
+	counts := make(map[sourcecollector.UnpackAction]int)
+	for _, result := range results {
+		counts[result.Action]++
+		if !dryRun {
+			fmt.Printf("%-9s %s\n", result.Action, result.Target)
+		}
+	}
+
+	prefix := ""
+	if dryRun {
+		prefix = "(dry run) "
+	}
+	fmt.Printf("%s%d to create, %d to overwrite, %d unchanged, %d skipped", prefix,
+		counts[sourcecollector.UnpackCreate], counts[sourcecollector.UnpackOverwrite], counts[sourcecollector.UnpackUnchanged], counts[sourcecollector.UnpackSkip])
+	if conflicts := counts[sourcecollector.UnpackConflict]; conflicts > 0 {
+		fmt.Printf(", %d conflicting (use --on-conflict skip or overwrite)", conflicts)
+	}
+	fmt.Println()
+}
+
+func init() {
+	unpackCmd.Flags().StringP("target", "t", ".", "Directory to recreate the files in")
+	unpackCmd.Flags().Int("strip-components", 0, "Remove this many leading directories from every path, e.g. 1 to drop the name of the input directory")
+	unpackCmd.Flags().String("on-conflict", string(sourcecollector.ConflictFail), "What to do with existing files with a different content: fail before writing anything, skip them or overwrite them")
+	unpackCmd.Flags().Bool("dry-run", false, "Print the diff of every file which would be created, overwritten or conflict, without writing anything")
+	rootCmd.AddCommand(unpackCmd)
+}
@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	sourcecollector "github.com/hitesh22rana/sourcecollector/pkg"
+
+	"github.com/spf13/cobra"
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Keep the output file up to date while the input files change",
+	Long: `Keep the output file up to date while the input files change.
+Builds the output, then rebuilds it whenever a collected file changes, ignoring the paths the collection ignores.
+Only the changed files are read again on a rebuild.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		debounce, _ := cmd.Flags().GetDuration("debounce")
+
+		// Watch until interrupted
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		sc, err := newCollectorFromFlags(cmd)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		fmt.Printf("👀 Watching %s, writing %s (Ctrl+C to stop)\n", sc.Input, sc.Output)
+
+		if err := sc.Watch(ctx, debounce, printWatchSummary); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// printWatchSummary prints which files of the output changed in a rebuild
+func printWatchSummary(summary *sourcecollector.WatchSummary) {
+	timestamp := time.Now().Format("15:04:05")
+
+	if summary.Err != nil {
+		fmt.Printf("[%s] ❌ %v\n", timestamp, summary.Err)
+		return
+	}
+
+	fmt.Printf("[%s] ✅ Rebuilt in %s", timestamp, summary.Duration.Round(time.Millisecond))
+	if changes := len(summary.Added) + len(summary.Modified) + len(summary.Removed); changes > 0 {
+		fmt.Printf(", %d file(s) changed", changes)
+	}
+	fmt.Println()
+
+	for _, path := range summary.Added {
+		fmt.Printf("   + %s\n", path)
+	}
+	for _, path := range summary.Modified {
+		fmt.Printf("   ~ %s\n", path)
+	}
+	for _, path := range summary.Removed {
+		fmt.Printf("   - %s\n", path)
+	}
+
+	if summary.Failed != nil {
+		reportFailedFiles(summary.Failed)
+	}
+}
+
+func init() {
+	addCollectorFlags(watchCmd.Flags())
+	watchCmd.Flags().Duration("debounce", 300*time.Millisecond, "Wait until the input files have not changed for this long before rebuilding")
+	watchCmd.MarkFlagRequired("input")
+	rootCmd.AddCommand(watchCmd)
+}
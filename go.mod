@@ -2,10 +2,14 @@ module github.com/hitesh22rana/sourcecollector
 
 go 1.22.1
 
-require github.com/spf13/cobra v1.8.0
+require (
+	github.com/fsnotify/fsnotify v1.7.0
+	github.com/sabhiram/go-gitignore v0.0.0-20210923224102-525f6e181f06
+	github.com/spf13/cobra v1.8.0
+	github.com/spf13/pflag v1.0.5
+)
 
 require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
-	github.com/sabhiram/go-gitignore v0.0.0-20210923224102-525f6e181f06 // indirect
-	github.com/spf13/pflag v1.0.5 // indirect
+	golang.org/x/sys v0.4.0 // indirect
 )
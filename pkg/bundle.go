@@ -0,0 +1,176 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// fenceLine matches the opening line of a fenced block, with an optional language tag added by an editor or model
+var fenceLine = regexp.MustCompile("^(`{3,})[^`]*$")
+
+// BundleFile is a single file parsed from a collected output
+type BundleFile struct {
+	// Path of the file relative to the base path of the collection
+	Path string `json:"path"`
+
+	// Content of the file
+	Content string `json:"content"`
+
+	// IdenticalTo is the path of an earlier file with the same content, set for the files written as a reference by a deduplicated collection
+	IdenticalTo string `json:"identicalTo,omitempty"`
+
+	// Transformed are the options which changed the content in the output, e.g. line-numbers, the content is then not the original file
+	Transformed []string `json:"transformed,omitempty"`
+}
+
+// ParseBundle parses the files out of a collected output in any of the output formats.
+// The tree structure and any text between the files, e.g. added by a model, is ignored.
+// The content of the files is kept byte for byte, unless every line of the output was converted to \r\n line endings after it was written.
+func ParseBundle(data []byte) ([]BundleFile, error) {
+	if crlf := bytes.Count(data, []byte("\r\n")); crlf > 0 && crlf == bytes.Count(data, []byte("\n")) {
+		data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	}
+
+	var (
+		files []BundleFile
+		err   error
+	)
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		files, err = parseJSONBundle(trimmed)
+	} else {
+		files, err = parseTextBundle(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return resolveDuplicates(files)
+}
+
+// resolveDuplicates fills the content of the files written as a reference from the file they refer to
+func resolveDuplicates(files []BundleFile) ([]BundleFile, error) {
+	originals := make(map[string]BundleFile, len(files))
+	for _, file := range files {
+		if file.IdenticalTo == "" {
+			originals[file.Path] = file
+		}
+	}
+
+	for i, file := range files {
+		if file.IdenticalTo == "" {
+			continue
+		}
+
+		original, ok := originals[file.IdenticalTo]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s is identical to %s, which is not in the bundle", ErrInvalidBundle, file.Path, file.IdenticalTo)
+		}
+		files[i].Content = original.Content
+		files[i].Transformed = original.Transformed
+	}
+
+	return files, nil
+}
+
+// parseJSONBundle parses the output of the json format
+func parseJSONBundle(data []byte) ([]BundleFile, error) {
+	var bundle struct {
+		Files []BundleFile `json:"files"`
+	}
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+
+	return bundle.Files, nil
+}
+
+// parseTextBundle parses the output of the text format, every file is a Name line, a Path line, optional Transformed and No newline at end of file lines and a fenced block or an Identical to line
+func parseTextBundle(data []byte) ([]BundleFile, error) {
+	lines := splitLines(data)
+
+	var files []BundleFile
+	for i := 0; i+2 < len(lines); i++ {
+		name, path, fence := trimNewline(lines[i]), trimNewline(lines[i+1]), trimNewline(lines[i+2])
+		if !strings.HasPrefix(name, "Name: ") || !strings.HasPrefix(path, "Path: ") {
+			continue
+		}
+
+		// The transforms which changed the content and a missing final newline are listed before its block
+		var (
+			transformed []string
+			noNewline   bool
+		)
+		block := i + 3
+		if transforms, ok := strings.CutPrefix(fence, "Transformed: "); ok && block < len(lines) {
+			for _, transform := range strings.Split(transforms, ",") {
+				transformed = append(transformed, strings.TrimSpace(transform))
+			}
+
+			fence = trimNewline(lines[block])
+			block++
+		}
+		if fence == noFinalNewline && block < len(lines) {
+			noNewline = true
+			fence = trimNewline(lines[block])
+			block++
+		}
+
+		// A duplicate refers to the earlier file with its content instead of a block
+		if original, ok := strings.CutPrefix(fence, "Identical to: "); ok {
+			files = append(files, BundleFile{
+				Path:        strings.TrimSpace(strings.TrimPrefix(path, "Path: ")),
+				IdenticalTo: strings.TrimSpace(original),
+			})
+
+			i = block - 1
+			continue
+		}
+
+		match := fenceLine.FindStringSubmatch(fence)
+		if match == nil {
+			continue
+		}
+
+		// The block ends at the first line which is exactly the opening fence
+		end := block
+		for end < len(lines) && strings.TrimRight(trimNewline(lines[end]), " \t") != match[1] {
+			end++
+		}
+		if end == len(lines) {
+			return nil, fmt.Errorf("%w: unterminated block of %s at line %d", ErrInvalidBundle, strings.TrimPrefix(path, "Path: "), block)
+		}
+
+		files = append(files, BundleFile{
+			Path:        strings.TrimSpace(strings.TrimPrefix(path, "Path: ")),
+			Content:     blockContent(lines[block:end], noNewline),
+			Transformed: transformed,
+		})
+
+		i = end
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("%w: no files found", ErrInvalidBundle)
+	}
+
+	return files, nil
+}
+
+// blockContent joins the lines of a fenced block, dropping the line break the writer adds before the closing fence.
+// A block without a blank line before its fence, e.g. written by a model, keeps its last line break unless the file has no final newline.
+func blockContent(lines [][]byte, noNewline bool) string {
+	content := string(bytes.Join(lines, nil))
+	if noNewline || content == "\n" || strings.HasSuffix(content, "\n\n") {
+		content = strings.TrimSuffix(content, "\n")
+	}
+
+	return content
+}
+
+// trimNewline removes the line break from the end of a line outside of the blocks
+func trimNewline(line []byte) string {
+	return strings.TrimRight(string(line), "\r\n")
+}
@@ -0,0 +1,119 @@
+package pkg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hitesh22rana/sourcecollector/pkg/cache"
+	"github.com/hitesh22rana/sourcecollector/pkg/outline"
+)
+
+// cacheVersion is part of every cache key, bump it when the transformations change their output
+const cacheVersion = 4
+
+// loadSourceFile returns the transformed content of the file and adds the secrets redacted from it to the redaction report
+func (sc *SourceCollector) loadSourceFile(path string, relPath string) (*cache.Blob, error) {
+	blob, err := sc.transformedSourceFile(path, relPath)
+	if err != nil {
+		return nil, err
+	}
+
+	sc.recordFindings(relPath, blob)
+	return blob, nil
+}
+
+// transformedSourceFile returns the transformed content of the file, only reading it if it changed since it was cached
+func (sc *SourceCollector) transformedSourceFile(path string, relPath string) (*cache.Blob, error) {
+	if sc.Cache == nil {
+		data, transcoded, err := readSourceFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		return sc.transformSourceFile(relPath, data, transcoded), nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key := sc.transformKey(relPath)
+
+	// Unchanged modification time and size, reuse the content hash without reading the file
+	if hash, ok := sc.Cache.Hash(path, info); ok {
+		if blob, ok := sc.Cache.Get(hash, key); ok {
+			return blob, nil
+		}
+	}
+
+	data, transcoded, err := readSourceFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// A transcoded file does not share its blobs with a file which was UTF-8 on disk, its blob is marked as transcoded
+	hash := cache.HashContent(data)
+	if transcoded {
+		hash = cache.HashContent([]byte("transcoded:" + hash))
+	}
+	sc.Cache.SetHash(path, info, hash)
+
+	// The file was touched but its content is the same, or it is a copy of another cached file
+	if blob, ok := sc.Cache.Get(hash, key); ok {
+		return blob, nil
+	}
+
+	blob := sc.transformSourceFile(relPath, data, transcoded)
+	sc.Cache.Put(hash, key, blob)
+
+	return blob, nil
+}
+
+// recordFindings adds the secrets redacted from the file to the redaction report
+func (sc *SourceCollector) recordFindings(relPath string, blob *cache.Blob) {
+	if sc.Redactor == nil {
+		return
+	}
+
+	for _, finding := range blob.Findings {
+		// A cached blob may have been redacted for a copy of the file at another path
+		finding.Path = relPath
+		sc.Redactor.Record(finding)
+	}
+}
+
+// transformKey identifies the options which change the transformed content of the file, blobs are only reused with the same options
+func (sc *SourceCollector) transformKey(relPath string) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "v%d|lines=%t|maxLine=%d|outline=%t", cacheVersion, sc.LineNumbers, sc.MaxLineLength, sc.Outline)
+
+	// The outline depends on the extractor of the file extension, the files without one are kept as they are
+	if sc.Outline && outline.ForPath(relPath) != nil {
+		fmt.Fprintf(hash, "|extractor=%s", strings.ToLower(filepath.Ext(relPath)))
+	}
+
+	if sc.SizeLimit != nil {
+		fmt.Fprintf(hash, "|size=%d%s:%s", sc.SizeLimit.Max, sc.SizeLimit.Unit, sc.SizeLimit.Policy)
+	}
+
+	if sc.Redactor != nil {
+		for _, detector := range sc.Redactor.Detectors {
+			fmt.Fprintf(hash, "|detector=%s:%s", detector.Kind, detector.Pattern)
+		}
+
+		allowlist := make([]string, 0, len(sc.Redactor.Allowlist))
+		for fingerprint := range sc.Redactor.Allowlist {
+			allowlist = append(allowlist, fingerprint)
+		}
+		sort.Strings(allowlist)
+		fmt.Fprintf(hash, "|allow=%v", allowlist)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
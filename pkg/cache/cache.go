@@ -0,0 +1,230 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hitesh22rana/sourcecollector/pkg/secrets"
+)
+
+const (
+	entriesDir = "entries"
+	blobsDir   = "blobs"
+)
+
+// Entry remembers the content hash of a file, so an unchanged file does not have to be read again
+type Entry struct {
+	// Path of the file
+	Path string `json:"path"`
+
+	// ModTime of the file in unix nanoseconds
+	ModTime int64 `json:"modTime"`
+
+	// Size of the file in bytes
+	Size int64 `json:"size"`
+
+	// Hash of the file content
+	Hash string `json:"hash"`
+}
+
+// Blob is the processed content of a file
+type Blob struct {
+	// Data is the transformed and redacted content
+	Data []byte `json:"data"`
+
+	// Tokens is the estimated number of LLM tokens of the data
+	Tokens int `json:"tokens"`
+
+	// Findings are the secrets redacted from the content
+	Findings []secrets.Finding `json:"findings,omitempty"`
+
+	// Transformed are the options which changed the data, so it differs from the file
+	Transformed []string `json:"transformed,omitempty"`
+
+	// Skipped is set if the file is left out of the output
+	Skipped bool `json:"skipped,omitempty"`
+}
+
+// Cache is an on-disk cache of processed files, keyed by path, modification time, size and content hash
+type Cache struct {
+	// Dir of the cache
+	Dir string
+}
+
+// Open opens the cache in the directory, creating it if needed
+func Open(dir string) (*Cache, error) {
+	for _, sub := range []string{entriesDir, blobsDir} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrOpenCache, err)
+		}
+	}
+
+	return &Cache{
+		Dir: dir,
+	}, nil
+}
+
+// HashContent returns the content hash used as the cache key
+func HashContent(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// Hash returns the cached content hash of the file if it did not change since it was cached
+func (c *Cache) Hash(path string, info fs.FileInfo) (string, bool) {
+	var entry Entry
+	if !c.read(c.entryPath(path), &entry) {
+		return "", false
+	}
+
+	if entry.Path != path || entry.ModTime != info.ModTime().UnixNano() || entry.Size != info.Size() {
+		return "", false
+	}
+
+	return entry.Hash, true
+}
+
+// SetHash remembers the content hash of the file
+func (c *Cache) SetHash(path string, info fs.FileInfo, hash string) {
+	c.write(c.entryPath(path), Entry{
+		Path:    path,
+		ModTime: info.ModTime().UnixNano(),
+		Size:    info.Size(),
+		Hash:    hash,
+	})
+}
+
+// Get returns the blob of the content hash processed with the options identified by key
+func (c *Cache) Get(hash string, key string) (*Blob, bool) {
+	var blob Blob
+	if !c.read(c.blobPath(hash, key), &blob) {
+		return nil, false
+	}
+
+	return &blob, true
+}
+
+// Put stores the blob of the content hash processed with the options identified by key
+func (c *Cache) Put(hash string, key string, blob *Blob) {
+	c.write(c.blobPath(hash, key), blob)
+}
+
+// Prune removes the entries of files which changed or no longer exist, the blobs no entry refers to, and everything not used for longer than maxAge if it is positive.
+// It returns the number of removed entries and blobs.
+func (c *Cache) Prune(maxAge time.Duration) (int, error) {
+	removed := 0
+	live := make(map[string]struct{})
+
+	entries, err := os.ReadDir(filepath.Join(c.Dir, entriesDir))
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrPruneCache, err)
+	}
+
+	for _, file := range entries {
+		path := filepath.Join(c.Dir, entriesDir, file.Name())
+
+		// Decode the entry without marking it as used, so it still goes stale
+		var entry Entry
+		if !c.isStale(path, maxAge) && decode(path, &entry) {
+			if info, err := os.Stat(entry.Path); err == nil && info.ModTime().UnixNano() == entry.ModTime && info.Size() == entry.Size {
+				live[entry.Hash] = struct{}{}
+				continue
+			}
+		}
+
+		if os.Remove(path) == nil {
+			removed++
+		}
+	}
+
+	blobs, err := os.ReadDir(filepath.Join(c.Dir, blobsDir))
+	if err != nil {
+		return removed, fmt.Errorf("%w: %v", ErrPruneCache, err)
+	}
+
+	for _, file := range blobs {
+		path := filepath.Join(c.Dir, blobsDir, file.Name())
+
+		hash, _, _ := strings.Cut(file.Name(), "-")
+		if _, ok := live[hash]; ok && !c.isStale(path, maxAge) {
+			continue
+		}
+
+		if os.Remove(path) == nil {
+			removed++
+		}
+	}
+
+	return removed, nil
+}
+
+// isStale checks if the cache file was not used for longer than maxAge
+func (c *Cache) isStale(path string, maxAge time.Duration) bool {
+	if maxAge <= 0 {
+		return false
+	}
+
+	info, err := os.Stat(path)
+	return err != nil || time.Since(info.ModTime()) > maxAge
+}
+
+// entryPath returns the path of the entry of the file
+func (c *Cache) entryPath(path string) string {
+	return filepath.Join(c.Dir, entriesDir, HashContent([]byte(path))+".json")
+}
+
+// blobPath returns the path of the blob of the content hash and options key
+func (c *Cache) blobPath(hash string, key string) string {
+	return filepath.Join(c.Dir, blobsDir, hash+"-"+key+".json")
+}
+
+// read decodes the cache file into value and marks it as used, a missing or corrupt file is a cache miss
+func (c *Cache) read(path string, value any) bool {
+	if !decode(path, value) {
+		return false
+	}
+
+	now := time.Now()
+	os.Chtimes(path, now, now)
+
+	return true
+}
+
+// decode decodes the cache file into value, it reports whether the file exists and is valid
+func decode(path string, value any) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+
+	return json.Unmarshal(data, value) == nil
+}
+
+// write stores the value in the cache file atomically, the cache is best effort so errors are ignored
+func (c *Cache) write(path string, value any) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return
+	}
+
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil || os.Rename(file.Name(), path) != nil {
+		os.Remove(file.Name())
+	}
+}
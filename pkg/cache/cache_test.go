@@ -0,0 +1,201 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// openCache opens a cache in a temporary directory
+func openCache(t *testing.T) *Cache {
+	t.Helper()
+
+	c, err := Open(filepath.Join(t.TempDir(), "cache"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+// writeFile writes the file with the modification time and returns its info
+func writeFile(t *testing.T, path string, content string, modTime time.Time) os.FileInfo {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return info
+}
+
+func TestHash(t *testing.T) {
+	c := openCache(t)
+	path := filepath.Join(t.TempDir(), "main.go")
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	info := writeFile(t, path, "package main\n", modTime)
+	if _, ok := c.Hash(path, info); ok {
+		t.Fatal("hash of a file which was never cached")
+	}
+
+	hash := HashContent([]byte("package main\n"))
+	c.SetHash(path, info, hash)
+
+	if got, ok := c.Hash(path, info); !ok || got != hash {
+		t.Fatalf("hash %q, %t, want %q", got, ok, hash)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		modTime time.Time
+	}{
+		{name: "modification time changed", content: "package main\n", modTime: modTime.Add(time.Second)},
+		{name: "size changed", content: "package main\n\n", modTime: modTime},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			changed := writeFile(t, path, test.content, test.modTime)
+			if _, ok := c.Hash(path, changed); ok {
+				t.Error("hash of a changed file was reused")
+			}
+		})
+	}
+}
+
+func TestHashCorruptEntry(t *testing.T) {
+	c := openCache(t)
+	path := filepath.Join(t.TempDir(), "main.go")
+	info := writeFile(t, path, "package main\n", time.Now())
+
+	c.SetHash(path, info, HashContent([]byte("package main\n")))
+	if err := os.WriteFile(c.entryPath(path), []byte(`{"path": "`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := c.Hash(path, info); ok {
+		t.Error("hash read from a corrupt entry")
+	}
+
+	// The corrupt entry is replaced by the next write
+	c.SetHash(path, info, "hash")
+	if got, ok := c.Hash(path, info); !ok || got != "hash" {
+		t.Errorf("hash %q, %t after rewriting the entry", got, ok)
+	}
+}
+
+func TestGetPut(t *testing.T) {
+	c := openCache(t)
+	hash := HashContent([]byte("package main\n"))
+
+	c.Put(hash, "plain", &Blob{Data: []byte("package main\n"), Tokens: 4})
+	c.Put(hash, "numbered", &Blob{Data: []byte("1 | package main\n"), Tokens: 5})
+
+	tests := []struct {
+		key    string
+		data   string
+		tokens int
+	}{
+		{key: "plain", data: "package main\n", tokens: 4},
+		{key: "numbered", data: "1 | package main\n", tokens: 5},
+	}
+
+	for _, test := range tests {
+		blob, ok := c.Get(hash, test.key)
+		if !ok {
+			t.Fatalf("no blob for the key %s", test.key)
+		}
+		if string(blob.Data) != test.data || blob.Tokens != test.tokens {
+			t.Errorf("blob for the key %s is %q with %d tokens, want %q with %d", test.key, blob.Data, blob.Tokens, test.data, test.tokens)
+		}
+	}
+
+	if _, ok := c.Get(hash, "outline"); ok {
+		t.Error("blob returned for a key it was not stored with")
+	}
+	if _, ok := c.Get(HashContent([]byte("package other\n")), "plain"); ok {
+		t.Error("blob returned for another content")
+	}
+}
+
+func TestPrune(t *testing.T) {
+	c := openCache(t)
+	dir := t.TempDir()
+
+	kept := filepath.Join(dir, "kept.go")
+	changed := filepath.Join(dir, "changed.go")
+	deleted := filepath.Join(dir, "deleted.go")
+
+	for _, path := range []string{kept, changed, deleted} {
+		info := writeFile(t, path, path, time.Now().Add(-time.Hour))
+		hash := HashContent([]byte(path))
+		c.SetHash(path, info, hash)
+		c.Put(hash, "key", &Blob{Data: []byte(path)})
+	}
+
+	// A blob no entry refers to
+	c.Put(HashContent([]byte("orphan")), "key", &Blob{})
+
+	writeFile(t, changed, "changed content", time.Now())
+	if err := os.Remove(deleted); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := c.Prune(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The entries and blobs of the changed and deleted files, and the orphan blob
+	if removed != 5 {
+		t.Errorf("removed %d files, want 5", removed)
+	}
+
+	info, _ := os.Stat(kept)
+	if _, ok := c.Hash(kept, info); !ok {
+		t.Error("the entry of the unchanged file was pruned")
+	}
+	if _, ok := c.Get(HashContent([]byte(kept)), "key"); !ok {
+		t.Error("the blob of the unchanged file was pruned")
+	}
+	if _, ok := c.Get(HashContent([]byte(changed)), "key"); ok {
+		t.Error("the blob of the changed file was kept")
+	}
+}
+
+func TestPruneMaxAge(t *testing.T) {
+	c := openCache(t)
+	path := filepath.Join(t.TempDir(), "main.go")
+	info := writeFile(t, path, "package main\n", time.Now())
+
+	hash := HashContent([]byte("package main\n"))
+	c.SetHash(path, info, hash)
+	c.Put(hash, "key", &Blob{})
+
+	// Not used for two days
+	old := time.Now().Add(-48 * time.Hour)
+	for _, cacheFile := range []string{c.entryPath(path), c.blobPath(hash, "key")} {
+		if err := os.Chtimes(cacheFile, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removed, err := c.Prune(24 * time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 2 {
+		t.Errorf("removed %d files, want 2", removed)
+	}
+}
@@ -0,0 +1,8 @@
+package cache
+
+import "errors"
+
+var (
+	ErrOpenCache  = errors.New("failed to open cache")
+	ErrPruneCache = errors.New("failed to prune cache")
+)
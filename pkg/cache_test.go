@@ -0,0 +1,43 @@
+package pkg
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/hitesh22rana/sourcecollector/pkg/cache"
+)
+
+func TestCacheKeyedByExtractor(t *testing.T) {
+	// The same content is outlined as Python and kept in full as text
+	src := "def total(items):\n    return sum(items)\n"
+	files := map[string]string{
+		"a.py":  src,
+		"a.txt": src,
+	}
+
+	cacheDir := t.TempDir()
+	for run := 0; run < 2; run++ {
+		c, err := cache.Open(cacheDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		bundle, err := ParseBundle(collect(t, files, FormatJSON, func(sc *SourceCollector) {
+			sc.Outline = true
+			sc.Cache = c
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := map[string]string{
+			filepath.Join("input", "a.py"):  "def total(items):\n",
+			filepath.Join("input", "a.txt"): src,
+		}
+		for _, file := range bundle {
+			if file.Content != want[file.Path] {
+				t.Errorf("run %d: %s content %q, want %q", run, file.Path, file.Content, want[file.Path])
+			}
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package pkg
+
+import (
+	"context"
+	"crypto/sha256"
+	"sync"
+
+	"github.com/hitesh22rana/sourcecollector/pkg/cache"
+)
+
+// duplicateIndex remembers the first file with every content, so the later files with the same content are written as a reference to it
+type duplicateIndex struct {
+	mu sync.Mutex
+
+	// first file with every content hash in the order of the tree, relative to the base path
+	byHash map[[sha256.Size]byte]string
+
+	// first file written in full with every content hash, relative to the base path
+	written map[[sha256.Size]byte]string
+
+	// originals of the indexed files by their path, empty for the first file with its content
+	originals map[string]string
+}
+
+// newDuplicateIndex makes an empty duplicate index
+func newDuplicateIndex() *duplicateIndex {
+	return &duplicateIndex{
+		byHash:    make(map[[sha256.Size]byte]string),
+		written:   make(map[[sha256.Size]byte]string),
+		originals: make(map[string]string),
+	}
+}
+
+// add indexes the file in the order of the tree, before the files are written
+func (d *duplicateIndex) add(file *processedFile) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	hash := duplicateHash(file)
+	original, ok := d.byHash[hash]
+	if !ok {
+		d.byHash[hash] = file.relPath
+	}
+
+	d.originals[file.node.Path] = original
+}
+
+// reference returns the relative path of the file written in full earlier with the same content, empty if the file is to be written in full.
+// Only the files which were actually written are referred to, so a reference never points ahead or to a file which could not be read, whatever the index said before.
+func (d *duplicateIndex) reference(file *processedFile) string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	original := d.written[duplicateHash(file)]
+	d.originals[file.node.Path] = original
+	return original
+}
+
+// write records the file as written in full, so the later files with the same content refer to it
+func (d *duplicateIndex) write(file *processedFile) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if hash := duplicateHash(file); d.written[hash] == "" {
+		d.written[hash] = file.relPath
+	}
+}
+
+// duplicateHash hashes the content of the file with the transforms which changed it, a reference takes the transforms of its original
+func duplicateHash(file *processedFile) [sha256.Size]byte {
+	hash := sha256.New()
+	for _, transform := range file.transformed {
+		hash.Write([]byte(transform + "\n"))
+	}
+	hash.Write([]byte{0})
+	hash.Write(file.data)
+
+	var sum [sha256.Size]byte
+	hash.Sum(sum[:0])
+	return sum
+}
+
+// originalOf returns the relative path of the file which the file at path is identical to, empty if it is not a duplicate
+func (d *duplicateIndex) originalOf(path string) string {
+	if d == nil {
+		return ""
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.originals[path]
+}
+
+// indexDuplicates indexes the content of the files of the source tree in the order of the output, so the tree can show the duplicates before they are written.
+// The content is transformed like in the output, so only files which end up the same are duplicates.
+func (sc *SourceCollector) indexDuplicates(ctx context.Context, sourceTree *SourceTree) error {
+	var (
+		filesMu sync.Mutex
+		files   = make(map[string]*processedFile)
+	)
+
+	sc.transformSourceFiles(ctx, sourceTree, func(node SourceNode, relPath string, blob *cache.Blob, err error) {
+		// The files which cannot be read are reported by the collection itself
+		if err != nil || blob.Skipped {
+			return
+		}
+
+		filesMu.Lock()
+		files[node.Path] = &processedFile{node: node, relPath: relPath, data: blob.Data, transformed: blob.Transformed}
+		filesMu.Unlock()
+	})
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Index in the order of the tree, so the first file with a content is also the first one written
+	index := newDuplicateIndex()
+	sc.forEachSourceFile(sourceTree, func(node SourceNode) {
+		if file, ok := files[node.Path]; ok {
+			index.add(file)
+		}
+	})
+
+	sc.duplicates = index
+	return nil
+}
@@ -0,0 +1,171 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hitesh22rana/sourcecollector/pkg/internal/testutil"
+)
+
+// duplicateFiles are copies of the same stub in several packages, by their path relative to the input directory
+var duplicateFiles = map[string]string{
+	"api/v1/stub.pb.go": "package stub\n",
+	"vendor/stub.pb.go": "package stub\n",
+	"web/stub.pb.go":    "package stub\n",
+	"web/main.go":       "package main\n",
+}
+
+func TestDedupe(t *testing.T) {
+	for _, format := range []Format{FormatText, FormatJSON} {
+		t.Run(string(format), func(t *testing.T) {
+			out := collect(t, duplicateFiles, format, func(sc *SourceCollector) {
+				sc.Dedupe = true
+			})
+
+			// The stub is written once, for its first path in the order of the output
+			if count := bytes.Count(out, []byte("package stub")); count != 1 {
+				t.Errorf("stub written %d times, want 1", count)
+			}
+
+			if count := bytes.Count(out, []byte("identical to input/vendor/stub.pb.go")); count != 2 {
+				t.Errorf("tree marks %d duplicates, want 2:\n%s", count, out)
+			}
+
+			// Unpacking restores the content of every duplicate
+			files, err := ParseBundle(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(files) != len(duplicateFiles) {
+				t.Fatalf("parsed %d files, want %d", len(files), len(duplicateFiles))
+			}
+
+			for _, file := range files {
+				if want := duplicateFiles[filepath.ToSlash(file.Path)[len("input/"):]]; file.Content != want {
+					t.Errorf("%s: content %q, want %q", file.Path, file.Content, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseBundleMissingOriginal(t *testing.T) {
+	_, err := ParseBundle([]byte("Name: b.go\nPath: input/b.go\nIdentical to: input/a.go\n\n"))
+	if err == nil {
+		t.Error("bundle with a reference to a missing file was parsed")
+	}
+}
+
+// assertReferencesBackward checks that every reference of the bundle points to a file written in full before it
+func assertReferencesBackward(t *testing.T, files []BundleFile) {
+	t.Helper()
+
+	written := make(map[string]bool)
+	for _, file := range files {
+		if file.IdenticalTo == "" {
+			written[file.Path] = true
+			continue
+		}
+
+		if !written[file.IdenticalTo] {
+			t.Errorf("%s refers to %s, which is not written in full before it", file.Path, file.IdenticalTo)
+		}
+	}
+}
+
+func TestDedupeConcurrent(t *testing.T) {
+	files := map[string]string{"main.go": "package main\n"}
+	for i := 0; i < 30; i++ {
+		files[fmt.Sprintf("pkg%d/stub.pb.go", i)] = "package stub\n" + strings.Repeat("// generated\n", 10000)
+	}
+
+	// The files are read in any order, but written in the order of the tree, so the references match the tree
+	for run := 0; run < 5; run++ {
+		out := collect(t, files, FormatText, func(sc *SourceCollector) {
+			sc.Dedupe = true
+			sc.MaxConcurrency = 8
+		})
+
+		if count := bytes.Count(out, []byte("\npackage stub\n")); count != 1 {
+			t.Errorf("stub written %d times, want 1", count)
+		}
+
+		bundle, err := ParseBundle(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(bundle) != len(files) {
+			t.Fatalf("parsed %d files, want %d", len(bundle), len(files))
+		}
+
+		assertReferencesBackward(t, bundle)
+
+		original := filepath.Join("input", "pkg0", "stub.pb.go")
+		for _, file := range bundle {
+			if file.IdenticalTo != "" && file.IdenticalTo != original {
+				t.Errorf("%s refers to %s, want %s", file.Path, file.IdenticalTo, original)
+			}
+		}
+
+		if count := bytes.Count(out, []byte("[identical to "+original+"]")); count != len(files)-2 {
+			t.Errorf("tree marks %d duplicates of %s, want %d:\n%s", count, original, len(files)-2, out)
+		}
+	}
+}
+
+func TestDedupeUnreadableOriginal(t *testing.T) {
+	input := testutil.WriteFiles(t, duplicateFiles)
+
+	sc, err := NewSourceCollector(input, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc.Validator = testutil.AcceptAll{}
+	sc.Dedupe = true
+
+	ctx := context.Background()
+	sourceTree, err := sc.GenerateSourceTree(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The tree picks vendor/stub.pb.go as the original, then it disappears before it is written
+	sourceTreeStructure, err := sc.GenerateSourceTreeStructure(ctx, sourceTree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(input, "vendor", "stub.pb.go")); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	err = sc.WriteSourceCode(ctx, &out, sourceTree, sourceTreeStructure)
+
+	var collectionErr *CollectionError
+	if !errors.As(err, &collectionErr) || len(collectionErr.Errors) != 1 {
+		t.Fatalf("error %v, want the missing original", err)
+	}
+
+	bundle, err := ParseBundle(out.Bytes())
+	if err != nil {
+		t.Fatalf("%v\n%s", err, out.Bytes())
+	}
+	assertReferencesBackward(t, bundle)
+
+	// The next copy in the order of the output is written in full instead
+	for _, file := range bundle {
+		if file.Path == filepath.Join("input", "web", "stub.pb.go") && file.IdenticalTo != "" {
+			t.Errorf("%s refers to %s, want it in full", file.Path, file.IdenticalTo)
+		}
+		if file.Path == filepath.Join("input", "api", "v1", "stub.pb.go") && file.IdenticalTo != filepath.Join("input", "web", "stub.pb.go") {
+			t.Errorf("%s refers to %q, want the copy written in full", file.Path, file.IdenticalTo)
+		}
+	}
+}
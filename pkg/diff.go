@@ -0,0 +1,145 @@
+package pkg
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// diffContext is the number of unchanged lines shown around every change
+	diffContext = 3
+
+	// maxDiffCells bounds the memory of the line matching, larger files are shown as replaced completely
+	maxDiffCells = 1 << 22
+)
+
+// diffLine is a line of a diff, kind is ' ' for an unchanged line, '-' for a removed line and '+' for an added line
+type diffLine struct {
+	kind byte
+	text string
+}
+
+// unifiedDiff returns the unified diff from the old to the new content, empty if they are equal
+func unifiedDiff(oldName string, newName string, oldContent string, newContent string) string {
+	if oldContent == newContent {
+		return ""
+	}
+
+	lines := diffLines(strings.SplitAfter(oldContent, "\n"), strings.SplitAfter(newContent, "\n"))
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", oldName, newName)
+
+	// Group the changes which are closer than twice the context into one hunk
+	for start := 0; start < len(lines); {
+		if lines[start].kind == ' ' {
+			start++
+			continue
+		}
+
+		first := max(start-diffContext, 0)
+		last, unchanged := start, 0
+		for end := start; end < len(lines) && unchanged <= 2*diffContext; end++ {
+			if lines[end].kind == ' ' {
+				unchanged++
+				continue
+			}
+			last, unchanged = end, 0
+		}
+		last = min(last+diffContext, len(lines)-1)
+
+		writeHunk(&sb, lines, first, last)
+		start = last + 1
+	}
+
+	return sb.String()
+}
+
+// writeHunk writes the lines from first to last as a single hunk
+func writeHunk(sb *strings.Builder, lines []diffLine, first int, last int) {
+	// Line numbers of the hunk start in the old and the new content
+	oldStart, newStart := 1, 1
+	for _, line := range lines[:first] {
+		if line.kind != '+' {
+			oldStart++
+		}
+		if line.kind != '-' {
+			newStart++
+		}
+	}
+
+	oldCount, newCount := 0, 0
+	for _, line := range lines[first : last+1] {
+		if line.kind != '+' {
+			oldCount++
+		}
+		if line.kind != '-' {
+			newCount++
+		}
+	}
+
+	fmt.Fprintf(sb, "@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount)
+	for _, line := range lines[first : last+1] {
+		sb.WriteByte(line.kind)
+		sb.WriteString(line.text)
+		if !strings.HasSuffix(line.text, "\n") {
+			sb.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// diffLines matches the lines of the old and the new content by their longest common subsequence
+func diffLines(oldLines []string, newLines []string) []diffLine {
+	// Drop the empty element after the trailing newline
+	if len(oldLines) > 0 && oldLines[len(oldLines)-1] == "" {
+		oldLines = oldLines[:len(oldLines)-1]
+	}
+	if len(newLines) > 0 && newLines[len(newLines)-1] == "" {
+		newLines = newLines[:len(newLines)-1]
+	}
+
+	n, m := len(oldLines), len(newLines)
+
+	// Too large to match, show the whole content as replaced
+	if (n+1)*(m+1) > maxDiffCells {
+		lines := make([]diffLine, 0, n+m)
+		for _, line := range oldLines {
+			lines = append(lines, diffLine{kind: '-', text: line})
+		}
+		for _, line := range newLines {
+			lines = append(lines, diffLine{kind: '+', text: line})
+		}
+		return lines
+	}
+
+	// common[i*(m+1)+j] is the length of the longest common subsequence of oldLines[i:] and newLines[j:]
+	common := make([]int32, (n+1)*(m+1))
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if oldLines[i] == newLines[j] {
+				common[i*(m+1)+j] = common[(i+1)*(m+1)+j+1] + 1
+			} else {
+				common[i*(m+1)+j] = max(common[(i+1)*(m+1)+j], common[i*(m+1)+j+1])
+			}
+		}
+	}
+
+	lines := make([]diffLine, 0, max(n, m))
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && oldLines[i] == newLines[j]:
+			lines = append(lines, diffLine{kind: ' ', text: oldLines[i]})
+			i++
+			j++
+		case j == m || (i < n && common[(i+1)*(m+1)+j] >= common[i*(m+1)+j+1]):
+			lines = append(lines, diffLine{kind: '-', text: oldLines[i]})
+			i++
+		default:
+			lines = append(lines, diffLine{kind: '+', text: newLines[j]})
+			j++
+		}
+	}
+
+	return lines
+}
@@ -1,15 +1,93 @@
 package pkg
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
 
 var (
 	ErrInvalidInputPath      = errors.New("input path is invalid")
 	ErrInvalidInputDirectory = errors.New("input path is not a valid directory")
 	ErrInvalidOutputPath     = errors.New("output path is invalid")
-	ErrFailedToCreateFile    = errors.New("failed to create output file")
 	ErrSourceTreeGeneration  = errors.New("failed to generate source tree")
 	ErrSourceTreeStructure   = errors.New("failed to generate source tree structure")
 	ErrSaveSourceTree        = errors.New("failed to save source tree to file")
 	ErrOpenOutputFile        = errors.New("failed to open output file")
+	ErrOutputExists          = errors.New("output file already exists")
+	ErrInvalidWriteMode      = errors.New("invalid write mode")
 	ErrWriteOutputFile       = errors.New("failed to write to output file")
+	ErrInvalidSizeLimit      = errors.New("invalid file size limit")
+	ErrCollectStats          = errors.New("failed to collect source code stats")
+	ErrPathIgnored           = errors.New("path is excluded from the collection")
+	ErrInvalidTreeOptions    = errors.New("invalid tree options")
+	ErrInvalidFormat         = errors.New("invalid output format")
+	ErrInvalidSymlinkPolicy  = errors.New("invalid symlink policy")
+	ErrInvalidTreePlacement  = errors.New("invalid tree placement")
+	ErrInvalidBundle         = errors.New("invalid bundle")
+	ErrUnsafePath            = errors.New("unsafe path")
+	ErrFileExists            = errors.New("file already exists")
+	ErrUnpack                = errors.New("failed to unpack bundle")
+	ErrTransformedFile       = errors.New("file was transformed for the output")
+	ErrWatch                 = errors.New("failed to watch source code")
+	ErrScanSecrets           = errors.New("failed to scan source code for secrets")
 )
+
+// FileError is the error of a single source code file which could not be collected
+type FileError struct {
+	// Path of the file relative to the base path
+	Path string
+
+	// Err is the underlying error
+	Err error
+}
+
+func (e *FileError) Error() string {
+	return e.Path + ": " + e.Err.Error()
+}
+
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+// CollectionError aggregates the errors of all the files which could not be collected
+type CollectionError struct {
+	// Errors of the files, sorted by path
+	Errors []*FileError
+}
+
+// newCollectionError creates a new CollectionError with the errors sorted by path
+func newCollectionError(fileErrors []*FileError) *CollectionError {
+	sort.Slice(fileErrors, func(i, j int) bool {
+		return fileErrors[i].Path < fileErrors[j].Path
+	})
+
+	return &CollectionError{
+		Errors: fileErrors,
+	}
+}
+
+func (e *CollectionError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Error()
+	}
+
+	return fmt.Sprintf("failed to collect %d file(s): %s", len(e.Errors), strings.Join(messages, "; "))
+}
+
+func (e *CollectionError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err
+	}
+
+	return errs
+}
+
+// isCollectionError checks if the error only reports files which could not be read
+func isCollectionError(err error) bool {
+	var collectionErr *CollectionError
+	return errors.As(err, &collectionErr)
+}
@@ -0,0 +1,88 @@
+package pkg
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hitesh22rana/sourcecollector/pkg/validators"
+)
+
+// SkippedPaths returns the paths excluded by the validator during the last GenerateSourceTree, sorted by path
+func (sc *SourceCollector) SkippedPaths() []SkippedPath {
+	sc.skippedMu.Lock()
+	defer sc.skippedMu.Unlock()
+
+	skipped := append([]SkippedPath{}, sc.skipped...)
+	sort.Slice(skipped, func(i, j int) bool {
+		return skipped[i].Path < skipped[j].Path
+	})
+
+	return skipped
+}
+
+// recordSkipped records a path excluded by the validator
+func (sc *SourceCollector) recordSkipped(path string, reason validators.Reason) {
+	relPath, _ := filepath.Rel(sc.BasePath, path)
+
+	sc.skippedMu.Lock()
+	sc.skipped = append(sc.skipped, SkippedPath{Path: relPath, Reason: reason})
+	sc.skippedMu.Unlock()
+}
+
+// Explain tells why a path is excluded from the collection by the validator or the symlink policy of the collector, like the walk does, a nil result means it is collected.
+// A relative path is relative to the input directory. A path is also excluded when one of its parent directories is, in which case the parent is returned.
+func (sc *SourceCollector) Explain(path string) (*SkippedPath, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(sc.Input, path)
+	}
+	path = filepath.Clean(path)
+
+	relPath, err := filepath.Rel(sc.Input, path)
+	if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("%w: %s is not inside %s", ErrInvalidInputPath, path, sc.Input)
+	}
+
+	if !isValidPath(path) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidInputPath, path)
+	}
+
+	if skipped := explainPath(sc.Validator, sc.Input, relPath); skipped != nil {
+		return skipped, nil
+	}
+
+	reason, err := sc.symlinkPathReason(relPath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidInputPath, path, err)
+	}
+	if reason != nil {
+		skippedPath, _ := filepath.Rel(sc.BasePath, path)
+		return &SkippedPath{Path: skippedPath, Reason: *reason}, nil
+	}
+
+	return nil, nil
+}
+
+// explainPath checks the input directory and every directory on the way down to the path relative to it, like the walk does
+func explainPath(validator validators.Validator, input string, relPath string) *SkippedPath {
+	basePath := filepath.Dir(input)
+
+	current := input
+	parts := strings.Split(relPath, string(filepath.Separator))
+	for i := 0; i <= len(parts); i++ {
+		if i > 0 {
+			if parts[i-1] == "." {
+				continue
+			}
+			current = filepath.Join(current, parts[i-1])
+		}
+
+		if ignored, reason := validator.IsIgnored(current); ignored {
+			skippedPath, _ := filepath.Rel(basePath, current)
+			return &SkippedPath{Path: skippedPath, Reason: reason}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/hitesh22rana/sourcecollector/pkg/internal/testutil"
+	"github.com/hitesh22rana/sourcecollector/pkg/validators"
+)
+
+func TestExplainMatchesWalk(t *testing.T) {
+	input := symlinkFixture(t)
+	testutil.WriteFilesIn(t, input, map[string]string{"docs/guide.md": "# Guide\n"})
+
+	paths := []string{
+		filepath.Join("src", "a.go"),
+		filepath.Join("alias", "a.go"),
+		filepath.Join("ext", "o.go"),
+		filepath.Join("src", "loop", "src", "a.go"),
+		filepath.Join("docs", "guide.md"),
+	}
+
+	for _, policy := range []SymlinkPolicy{SymlinkSkip, SymlinkFollowWithinRoot, SymlinkFollow} {
+		t.Run(string(policy), func(t *testing.T) {
+			sc, err := NewSourceCollector(input, "", false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sc.Symlinks = policy
+			sc.Validator, err = validators.NewPatternValidator(testutil.AcceptAll{}, input, nil, []string{"docs/**"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			sourceTree, err := sc.GenerateSourceTree(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			collected := make(map[string]bool)
+			for _, path := range sc.SourceFilePaths(sourceTree) {
+				collected[path] = true
+			}
+
+			// A path is explained as collected exactly when the walk collects it
+			for _, path := range paths {
+				skipped, err := sc.Explain(path)
+				if err != nil {
+					t.Fatalf("%s: %v", path, err)
+				}
+
+				if want := collected[filepath.Join("input", path)]; (skipped == nil) != want {
+					t.Errorf("%s: explained as %v, collected by the walk: %v", path, skipped, want)
+				}
+			}
+		})
+	}
+}
+
+func TestExplain(t *testing.T) {
+	input := symlinkFixture(t)
+
+	sc, err := NewSourceCollector(input, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc.Validator = testutil.AcceptAll{}
+
+	skipped, err := sc.Explain(filepath.Join("ext", "o.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if skipped == nil || skipped.Rule != validators.RuleSymlink || skipped.Path != filepath.Join("input", "ext", "o.go") {
+		t.Errorf("link outside the root explained as %+v", skipped)
+	}
+
+	// An absolute path works as well as one relative to the input
+	if skipped, err := sc.Explain(filepath.Join(input, "src", "a.go")); err != nil || skipped != nil {
+		t.Errorf("collected file explained as %+v, error %v", skipped, err)
+	}
+
+	if _, err := sc.Explain(filepath.Join("..", "outside", "o.go")); !errors.Is(err, ErrInvalidInputPath) {
+		t.Errorf("path outside the input: error %v, want %v", err, ErrInvalidInputPath)
+	}
+}
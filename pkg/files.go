@@ -0,0 +1,93 @@
+package pkg
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SourceFile is a single file of the collection with its transformed content
+type SourceFile struct {
+	// Name of the file
+	Name string `json:"name"`
+
+	// Path of the file relative to the base path, as in the output
+	Path string `json:"path"`
+
+	// Content of the file after the size limit, redaction and the other transformations
+	Content string `json:"content"`
+
+	// Tokens is the estimated number of LLM tokens of the content
+	Tokens int `json:"tokens"`
+
+	// Transformed are the options which changed the content, so it differs from the file
+	Transformed []string `json:"transformed,omitempty"`
+
+	// SizeStatus of the file, set if it was truncated or skipped because of the size limit
+	SizeStatus SizeStatus `json:"sizeStatus,omitempty"`
+}
+
+// SourceFilePaths returns the paths of the files of the source tree relative to the base path, in the order of the output
+func (sc *SourceCollector) SourceFilePaths(sourceTree *SourceTree) []string {
+	var paths []string
+	sc.forEachSourceFile(sourceTree, func(node SourceNode) {
+		relPath, _ := filepath.Rel(sc.BasePath, node.Path)
+		paths = append(paths, relPath)
+	})
+
+	return paths
+}
+
+// ReadSourceFile reads a single file of the collection by its path relative to the base path, as in the output.
+// Paths outside of the input directory or excluded from the collection cannot be read.
+func (sc *SourceCollector) ReadSourceFile(relPath string) (*SourceFile, error) {
+	path := filepath.Join(sc.BasePath, filepath.FromSlash(relPath))
+
+	inputRelPath, err := filepath.Rel(sc.Input, path)
+	if err != nil || filepath.IsAbs(relPath) || inputRelPath == ".." || strings.HasPrefix(inputRelPath, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("%w: %s is not inside %s", ErrInvalidInputPath, relPath, filepath.Base(sc.Input))
+	}
+
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return nil, fmt.Errorf("%w: %s is not a file", ErrInvalidInputPath, relPath)
+	}
+
+	if skipped := explainPath(sc.Validator, sc.Input, inputRelPath); skipped != nil {
+		return nil, fmt.Errorf("%w: %s: %s", ErrPathIgnored, skipped.Path, skipped.Reason)
+	}
+
+	reason, err := sc.symlinkPathReason(inputRelPath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s is not a file: %v", ErrInvalidInputPath, relPath, err)
+	}
+	if reason != nil {
+		return nil, fmt.Errorf("%w: %s: %s", ErrPathIgnored, relPath, reason)
+	}
+
+	relPath, _ = filepath.Rel(sc.BasePath, path)
+
+	blob, err := sc.loadSourceFile(path, relPath)
+	if err != nil {
+		return nil, &FileError{Path: relPath, Err: err}
+	}
+
+	file := &SourceFile{
+		Name:        extractName(path),
+		Path:        relPath,
+		Content:     string(blob.Data),
+		Tokens:      blob.Tokens,
+		Transformed: blob.Transformed,
+	}
+
+	// Tell why the content is missing or incomplete
+	file.SizeStatus = blobSizeStatus(blob)
+
+	return file, nil
+}
+
+// FormatSourceFile formats a single file like in the output of the collector
+func (sc *SourceCollector) FormatSourceFile(file *SourceFile) string {
+	return sc.formatter().file(file.Name, file.Path, []byte(file.Content), file.Transformed)
+}
@@ -0,0 +1,183 @@
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Format is the format of the collected output
+type Format string
+
+const (
+	// FormatText writes every file as a Name/Path header followed by a fenced block
+	FormatText Format = "text"
+
+	// FormatJSON writes a single JSON object with the tree structure and the list of files
+	FormatJSON Format = "json"
+)
+
+// Transform is an option which changes the content of the files in the output, named after its flag
+type Transform string
+
+const (
+	// TransformEncoding is the transcoding of a UTF-16 or Latin-1 file to UTF-8, which has no flag
+	TransformEncoding      Transform = "encoding"
+	TransformOutline       Transform = "outline"
+	TransformMaxFileSize   Transform = "max-file-size"
+	TransformRedact        Transform = "redact"
+	TransformMaxLineLength Transform = "max-line-length"
+	TransformLineNumbers   Transform = "line-numbers"
+)
+
+// ParseFormat parses the name of an output format
+func ParseFormat(name string) (Format, error) {
+	switch format := Format(strings.ToLower(name)); format {
+	case FormatText, FormatJSON:
+		return format, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrInvalidFormat, name)
+	}
+}
+
+// formatter writes the parts of the output in one format
+type formatter interface {
+	// header starts the output with the source tree structure
+	header(sourceTreeStructure string) string
+
+	// file formats a single file, with the transforms which changed its content
+	file(name string, relPath string, data []byte, transformed []string) string
+
+	// duplicate formats a file with the same content as the earlier file at originalPath
+	duplicate(name string, relPath string, originalPath string) string
+
+	// separator goes between two files
+	separator() string
+
+	// footer ends the output, with the source tree structure if it was not in the header
+	footer(sourceTreeStructure string) string
+}
+
+// formatter returns the formatter of the output format of the collector
+func (sc *SourceCollector) formatter() formatter {
+	if sc.Format == FormatJSON {
+		return jsonFormatter{}
+	}
+
+	return textFormatter{}
+}
+
+// noFinalNewline marks the files without a newline at the end in the text format, as the line break before the closing fence is not part of their content
+const noFinalNewline = "No newline at end of file"
+
+// textFormatter writes the plain text format
+type textFormatter struct{}
+
+func (textFormatter) header(sourceTreeStructure string) string {
+	if sourceTreeStructure == "" {
+		return ""
+	}
+
+	return "Source code files structure\n\n" + sourceTreeStructure + "\n\n"
+}
+
+func (textFormatter) file(name string, relPath string, data []byte, transformed []string) string {
+	fence := fenceFor(data)
+
+	var sb strings.Builder
+
+	sb.WriteString("Name: " + name + "\nPath: " + relPath + "\n")
+	if len(transformed) > 0 {
+		sb.WriteString("Transformed: " + strings.Join(transformed, ", ") + "\n")
+	}
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		sb.WriteString(noFinalNewline + "\n")
+	}
+	sb.WriteString(fence + "\n")
+	sb.Write(data)
+	sb.WriteString("\n" + fence + "\n\n")
+
+	return sb.String()
+}
+
+func (textFormatter) duplicate(name string, relPath string, originalPath string) string {
+	return "Name: " + name + "\nPath: " + relPath + "\nIdentical to: " + originalPath + "\n\n"
+}
+
+// fenceFor returns a fence longer than the longest run of backticks in the content, so the content can never close its block
+func fenceFor(data []byte) string {
+	longest, run := 0, 0
+	for _, b := range data {
+		if b != '`' {
+			run = 0
+			continue
+		}
+
+		run++
+		longest = max(longest, run)
+	}
+
+	return strings.Repeat("`", max(3, longest+1))
+}
+
+func (textFormatter) separator() string {
+	return ""
+}
+
+func (textFormatter) footer(sourceTreeStructure string) string {
+	if sourceTreeStructure == "" {
+		return ""
+	}
+
+	return "Source code files structure\n\n" + sourceTreeStructure + "\n\n"
+}
+
+// jsonFormatter writes a JSON object, one file per line so it can be streamed
+type jsonFormatter struct{}
+
+// jsonFile is a single file in the JSON format
+type jsonFile struct {
+	Name        string   `json:"name"`
+	Path        string   `json:"path"`
+	Transformed []string `json:"transformed,omitempty"`
+	Content     string   `json:"content"`
+}
+
+// jsonDuplicate is a file of the json output with the same content as an earlier file
+type jsonDuplicate struct {
+	Name        string `json:"name"`
+	Path        string `json:"path"`
+	IdenticalTo string `json:"identicalTo"`
+}
+
+func (jsonFormatter) header(sourceTreeStructure string) string {
+	if sourceTreeStructure == "" {
+		return `{"files":[` + "\n"
+	}
+
+	tree, _ := json.Marshal(sourceTreeStructure)
+	return `{"tree":` + string(tree) + `,"files":[` + "\n"
+}
+
+func (jsonFormatter) file(name string, relPath string, data []byte, transformed []string) string {
+	file, _ := json.Marshal(jsonFile{Name: name, Path: relPath, Transformed: transformed, Content: string(data)})
+	return string(file)
+}
+
+func (jsonFormatter) duplicate(name string, relPath string, originalPath string) string {
+	file, _ := json.Marshal(jsonDuplicate{Name: name, Path: relPath, IdenticalTo: originalPath})
+	return string(file)
+}
+
+func (jsonFormatter) separator() string {
+	return ",\n"
+}
+
+func (jsonFormatter) footer(sourceTreeStructure string) string {
+	if sourceTreeStructure == "" {
+		return "\n]}\n"
+	}
+
+	tree, _ := json.Marshal(sourceTreeStructure)
+	return "\n]," + `"tree":` + string(tree) + "}\n"
+}
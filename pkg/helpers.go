@@ -1,71 +1,364 @@
 package pkg
 
 import (
+	"bytes"
+	"context"
 	"os"
 	"path/filepath"
+	"sync"
+
+	"github.com/hitesh22rana/sourcecollector/pkg/cache"
+	"github.com/hitesh22rana/sourcecollector/pkg/secrets"
+	"github.com/hitesh22rana/sourcecollector/pkg/validators"
 )
 
-// Helper function for GenerateSourceTree
-func (sc *SourceCollector) generateSourceTree(path string) *SourceTree {
+// generateSourceTree walks the path with up to sc.MaxConcurrency goroutines reading directories at the same time.
+// The children of every directory keep the order of os.ReadDir, so the tree is the same on every run.
+func (sc *SourceCollector) generateSourceTree(ctx context.Context, path string) *SourceTree {
+	// Stop walking once the context is done
+	if ctx.Err() != nil {
+		return nil
+	}
+
 	// Check if the path is valid or not and if it is a supported file
 	fileInfo, err := os.Stat(path)
-	if err != nil || sc.Validator.IsIgnored(path) {
+	if err != nil {
+		return nil
+	}
+
+	if ignored, reason := validators.IsIgnoredEntry(sc.Validator, path, fileInfo.IsDir()); ignored {
+		sc.recordSkipped(path, reason)
 		return nil
 	}
 
 	// If the path is not a directory, return the source node
 	if !fileInfo.IsDir() {
-		return &SourceTree{
-			Root: &SourceNode{
-				Name: extractName(path),
-				Path: path,
-			},
-			Nodes: nil,
+		return sc.sourceFileTree(path, fileInfo.Size)
+	}
+
+	sourceTree := newSourceDirectoryTree(path, "")
+
+	walk := &treeWalk{
+		slots: make(chan struct{}, max(sc.MaxConcurrency-1, 0)),
+		root:  sc.symlinkRoot(),
+	}
+
+	ok := sc.readSourceDirectory(ctx, walk, sourceTree, &walkAncestor{path: path})
+	walk.wg.Wait()
+
+	if !ok {
+		return nil
+	}
+
+	return sourceTree
+}
+
+// treeWalk is the state shared by the goroutines of generateSourceTree
+type treeWalk struct {
+	wg sync.WaitGroup
+
+	// slots bound the number of goroutines reading directories besides the one which started the walk
+	slots chan struct{}
+
+	// root is the input with its symbolic links resolved
+	root string
+}
+
+// readSourceDirectory fills the nodes of the directory tree, reading its subdirectories in new goroutines tracked by walk while a slot is free, in this one otherwise.
+// The type of every entry comes from the directory listing, so only symbolic links are stat'ed.
+// It returns false if the directory could not be read, in which case it is left out of the tree.
+func (sc *SourceCollector) readSourceDirectory(ctx context.Context, walk *treeWalk, sourceTree *SourceTree, ancestors *walkAncestor) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	entries, err := os.ReadDir(sourceTree.Root.Path)
+	if err != nil {
+		return false
+	}
+
+	// Every entry has its own slot, ignored entries stay nil
+	sourceTree.Nodes = make([]*SourceTree, len(entries))
+	for i, entry := range entries {
+		if ctx.Err() != nil {
+			return false
+		}
+
+		e, ok := sc.resolveEntry(filepath.Join(sourceTree.Root.Path, entry.Name()), entry, walk.root, ancestors)
+		if !ok {
+			continue
+		}
+
+		if ignored, reason := validators.IsIgnoredEntry(sc.Validator, e.path, e.isDir); ignored {
+			sc.recordSkipped(e.path, reason)
+			continue
+		}
+
+		if !e.isDir {
+			node := sc.sourceFileTree(e.path, e.size)
+			node.Root.LinkTarget = e.link
+			sourceTree.Nodes[i] = node
+			continue
+		}
+
+		child := newSourceDirectoryTree(e.path, e.link)
+		childAncestors := &walkAncestor{path: child.Root.Path, parent: ancestors}
+
+		select {
+		case walk.slots <- struct{}{}:
+			walk.wg.Add(1)
+			go func(i int) {
+				defer walk.wg.Done()
+				defer func() { <-walk.slots }()
+
+				if sc.readSourceDirectory(ctx, walk, child, childAncestors) {
+					sourceTree.Nodes[i] = child
+				}
+			}(i)
+		default:
+			if sc.readSourceDirectory(ctx, walk, child, childAncestors) {
+				sourceTree.Nodes[i] = child
+			}
 		}
 	}
 
-	// If the path is a directory, create a source tree
-	var sourceTree SourceTree = SourceTree{
+	return true
+}
+
+// newSourceDirectoryTree makes the tree of a directory without any nodes yet, link is the target if it is a symbolic link
+func newSourceDirectoryTree(path string, link string) *SourceTree {
+	return &SourceTree{
 		Root: &SourceNode{
-			Name: extractName(path),
-			Path: path,
+			Name:       extractName(path),
+			Path:       path,
+			LinkTarget: link,
 		},
 		Nodes: []*SourceTree{},
 	}
+}
 
-	// Get all the directories and files in the path
-	files, err := os.ReadDir(path)
-	if err != nil {
-		return nil
+// sourceFileTree makes the tree of a file, size is only called when a size limit is set
+func (sc *SourceCollector) sourceFileTree(path string, size func() int64) *SourceTree {
+	var sizeStatus SizeStatus
+	if sc.SizeLimit != nil {
+		sizeStatus = sc.sizeStatus(path, size())
 	}
 
-	for _, file := range files {
-		// Get the source tree of the file
-		sourceTree.Nodes = append(sourceTree.Nodes, sc.generateSourceTree(filepath.Join(path, file.Name())))
+	return &SourceTree{
+		Root: &SourceNode{
+			Name:       extractName(path),
+			Path:       path,
+			SizeStatus: sizeStatus,
+		},
+		Nodes: nil,
 	}
-
-	return &sourceTree
 }
 
-func (sc *SourceCollector) generateSourceTreeStructure(tree *SourceTree, level int) string {
-	// Generate the tree structure
-	var treeStructure string
-	for range level {
-		treeStructure += "│  "
+// sizeStatus tells if the file at path is over the size limit, judged by its size on disk if it cannot be.
+// Otherwise it is measured on its content as transformed for the output, outline included, which the cache keeps for the output.
+func (sc *SourceCollector) sizeStatus(path string, size int64) SizeStatus {
+	if !sc.SizeLimit.mayExceed(size) {
+		return ""
+	}
+
+	relPath, _ := filepath.Rel(sc.BasePath, path)
+	blob, err := sc.transformedSourceFile(path, relPath)
+	if err != nil {
+		return ""
 	}
 
-	treeStructure += "├──" + tree.Root.Name + "\n"
+	return blobSizeStatus(blob)
+}
+
+// forEachSourceFile calls fn for every file of the source tree in BFS order, skipping the output file
+func (sc *SourceCollector) forEachSourceFile(sourceTree *SourceTree, fn func(node SourceNode)) {
+	queue := []*SourceTree{sourceTree}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
 
-	for _, node := range tree.Nodes {
 		// Check if the node is nil
 		if node == nil {
 			continue
 		}
 
-		// Generate the source tree structure
-		subTreeStructure := sc.generateSourceTreeStructure(node, level+1)
-		treeStructure += subTreeStructure
+		for _, node := range node.Nodes {
+			// Check if the node is nil or the node is the output path
+			if node == nil || node.Root.Path == sc.Output {
+				continue
+			}
+
+			// Check if the node is a directory or a file, if it is a directory, add it to the queue and continue
+			if node.Nodes != nil {
+				queue = append(queue, node)
+				continue
+			}
+
+			fn(*node.Root)
+		}
+	}
+}
+
+// queuedFile is a source code file waiting to be read, seq is its position in the order the files were produced
+type queuedFile struct {
+	node SourceNode
+	seq  int
+}
+
+// processedFile is a source code file ready to be formatted for the output
+type processedFile struct {
+	node        SourceNode
+	relPath     string
+	data        []byte
+	transformed []string
+	tokens      int
+	seq         int
+}
+
+// processSourceFile reads a source code file and transforms it for the output, a nil result means the file is skipped
+func (sc *SourceCollector) processSourceFile(node SourceNode) (*processedFile, *FileError) {
+	// Get the relative path of the file
+	relPath, _ := filepath.Rel(sc.BasePath, node.Path)
+
+	// Load the transformed content, from the cache if the file did not change
+	blob, err := sc.loadSourceFile(node.Path, relPath)
+	if err != nil {
+		return nil, &FileError{Path: relPath, Err: err}
+	}
+
+	// Skip the files left out because of the size limit
+	if blob.Skipped {
+		return nil, nil
+	}
+
+	return &processedFile{node: node, relPath: relPath, data: blob.Data, transformed: blob.Transformed, tokens: blob.Tokens}, nil
+}
+
+// duplicateOf returns the relative path of the file written in full earlier with the same content, empty if the file is to be written in full.
+// It must be called in the order the files are written.
+func (sc *SourceCollector) duplicateOf(file *processedFile) string {
+	if !sc.Dedupe || sc.duplicates == nil {
+		return ""
+	}
+
+	return sc.duplicates.reference(file)
+}
+
+// wroteSourceFile records a file written in full, so the later files with the same content refer to it
+func (sc *SourceCollector) wroteSourceFile(file *processedFile) {
+	if sc.Dedupe && sc.duplicates != nil {
+		sc.duplicates.write(file)
+	}
+}
+
+// transformSourceFile applies the outline, the size limit, redaction, line truncation and line numbering to the content of a file, transcoded tells if it was read from another encoding than UTF-8.
+// The transforms which changed the content are recorded, so a bundle of the output is not mistaken for the original files.
+func (sc *SourceCollector) transformSourceFile(relPath string, data []byte, transcoded bool) *cache.Blob {
+	var transformed []string
+	if transcoded {
+		transformed = append(transformed, string(TransformEncoding))
+	}
+
+	// Outline the file first, so the rest applies to what ends up in the output
+	if outlined := sc.outlineSourceFile(relPath, data); !bytes.Equal(outlined, data) {
+		data = outlined
+		transformed = append(transformed, string(TransformOutline))
 	}
 
-	return treeStructure
+	// Work out which lines fit in the size limit, before the content is transformed
+	head, tail := -1, 0
+	if sc.SizeLimit != nil {
+		var sizeStatus SizeStatus
+		head, tail, sizeStatus = sc.SizeLimit.keep(data)
+		if sizeStatus == SizeStatusSkipped {
+			return &cache.Blob{Skipped: true}
+		}
+	}
+
+	// Redact the secrets before the content leaves the file
+	var findings []secrets.Finding
+	if sc.Redactor != nil {
+		data, findings = sc.Redactor.Apply(relPath, data)
+		if len(findings) > 0 {
+			transformed = append(transformed, string(TransformRedact))
+		}
+	}
+
+	// Truncate the long lines after redaction, so no part of a secret is left behind
+	if truncated := truncateLines(data, sc.MaxLineLength); !bytes.Equal(truncated, data) {
+		data = truncated
+		transformed = append(transformed, string(TransformMaxLineLength))
+	}
+
+	// Number the lines after redaction, the numbers are part of the content so the text and json formats carry the same ones
+	if sc.LineNumbers && len(data) > 0 {
+		data = numberLines(data)
+		transformed = append(transformed, string(TransformLineNumbers))
+	}
+
+	// Cut the file down to the size limit, the transformations above keep the line count so the numbers stay correct
+	if head >= 0 {
+		data = elide(data, head, tail)
+		transformed = append(transformed, string(TransformMaxFileSize))
+	}
+
+	return &cache.Blob{
+		Data:        data,
+		Tokens:      estimateTokens(data),
+		Findings:    findings,
+		Transformed: transformed,
+	}
+}
+
+// readSourceFiles reads the files of the source tree with sc.MaxConcurrency goroutines and calls fn for each of them, fn must be safe for concurrent use.
+// The files left are not read once the context is done.
+func (sc *SourceCollector) readSourceFiles(ctx context.Context, sourceTree *SourceTree, fn func(node SourceNode, relPath string, content []byte, err error)) {
+	sc.eachSourceFile(ctx, sourceTree, func(node SourceNode, relPath string) {
+		content, _, err := readSourceFile(node.Path)
+		fn(node, relPath, content, err)
+	})
+}
+
+// transformSourceFiles loads the transformed content of the files of the source tree like readSourceFiles, from the cache if the files did not change.
+// The secrets redacted from the files are not added to the redaction report.
+func (sc *SourceCollector) transformSourceFiles(ctx context.Context, sourceTree *SourceTree, fn func(node SourceNode, relPath string, blob *cache.Blob, err error)) {
+	sc.eachSourceFile(ctx, sourceTree, func(node SourceNode, relPath string) {
+		blob, err := sc.transformedSourceFile(node.Path, relPath)
+		fn(node, relPath, blob, err)
+	})
+}
+
+// eachSourceFile calls fn for each file of the source tree from sc.MaxConcurrency goroutines, until the context is done
+func (sc *SourceCollector) eachSourceFile(ctx context.Context, sourceTree *SourceTree, fn func(node SourceNode, relPath string)) {
+	var wg sync.WaitGroup
+	queueChan := make(chan SourceNode)
+
+	for i := 0; i < sc.MaxConcurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for queueData := range queueChan {
+				// Drain the queue without reading once the context is done
+				if ctx.Err() != nil {
+					continue
+				}
+
+				// Get the relative path of the file
+				relPath, _ := filepath.Rel(sc.BasePath, queueData.Path)
+
+				fn(queueData, relPath)
+			}
+		}()
+	}
+
+	sc.forEachSourceFile(sourceTree, func(node SourceNode) {
+		select {
+		case queueChan <- node:
+		case <-ctx.Done():
+		}
+	})
+	close(queueChan)
+
+	wg.Wait()
 }
@@ -0,0 +1,42 @@
+// Package testutil holds the fixtures shared by the tests of the collector and its servers
+package testutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hitesh22rana/sourcecollector/pkg/validators"
+)
+
+// AcceptAll is a validator which collects every path, as the default rules ignore the temporary directories of the tests
+type AcceptAll struct{}
+
+func (AcceptAll) IsIgnored(string) (bool, validators.Reason) {
+	return false, validators.Reason{}
+}
+
+// WriteFiles writes the files into an input directory of a new temporary directory and returns the input, the files are keyed by their slash separated path relative to it
+func WriteFiles(t testing.TB, files map[string]string) string {
+	t.Helper()
+
+	input := filepath.Join(t.TempDir(), "input")
+	WriteFilesIn(t, input, files)
+
+	return input
+}
+
+// WriteFilesIn writes the files into the directory, creating the directories of their paths
+func WriteFilesIn(t testing.TB, dir string, files map[string]string) {
+	t.Helper()
+
+	for path, content := range files {
+		path = filepath.Join(dir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package pkg
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/hitesh22rana/sourcecollector/pkg/cache"
+)
+
+// SizeUnit is the unit in which the size of a file is measured
+type SizeUnit string
+
+const (
+	SizeUnitBytes  SizeUnit = "bytes"
+	SizeUnitLines  SizeUnit = "lines"
+	SizeUnitTokens SizeUnit = "tokens"
+)
+
+// OversizePolicy decides what happens to a file which exceeds the size limit
+type OversizePolicy string
+
+const (
+	// OversizeSkip leaves the file out of the output
+	OversizeSkip OversizePolicy = "skip"
+
+	// OversizeHead keeps the first lines of the file which fit in the limit
+	OversizeHead OversizePolicy = "head"
+
+	// OversizeHeadTail keeps the first and the last lines of the file which fit in the limit, with an elision marker in between
+	OversizeHeadTail OversizePolicy = "head-tail"
+)
+
+// SizeStatus tells if a file was cut or left out because of the size limit
+type SizeStatus string
+
+const (
+	SizeStatusTruncated SizeStatus = "truncated"
+	SizeStatusSkipped   SizeStatus = "skipped"
+)
+
+var (
+	// sizeLimitFormat matches a size limit like 500, 64KB, 2000lines or 8000tokens
+	sizeLimitFormat = regexp.MustCompile(`^(\d+)\s*([a-zA-Z]*)$`)
+
+	// elisionMarker matches the line which replaces the lines cut out of a file by the size limit
+	elisionMarker = regexp.MustCompile(`^… \[(\d+) lines omitted\] …$`)
+)
+
+// SizeLimit is the maximum size of a single file and what to do with files exceeding it
+type SizeLimit struct {
+	// Max size in the unit
+	Max int
+
+	// Unit of the max size
+	Unit SizeUnit
+
+	// Policy for the files exceeding the max size
+	Policy OversizePolicy
+}
+
+// ParseSizeLimit parses a size limit like 500 (bytes), 64KB, 1MB, 2000lines or 8000tokens with the given policy
+func ParseSizeLimit(value string, policy OversizePolicy) (*SizeLimit, error) {
+	match := sizeLimitFormat.FindStringSubmatch(strings.TrimSpace(value))
+	if match == nil {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidSizeLimit, value)
+	}
+
+	max, err := strconv.Atoi(match[1])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidSizeLimit, value)
+	}
+
+	unit, multiplier := SizeUnitBytes, 1
+	switch strings.ToLower(match[2]) {
+	case "", "b", "byte", "bytes":
+	case "k", "kb":
+		multiplier = 1 << 10
+	case "m", "mb":
+		multiplier = 1 << 20
+	case "g", "gb":
+		multiplier = 1 << 30
+	case "l", "line", "lines":
+		unit = SizeUnitLines
+	case "t", "token", "tokens":
+		unit = SizeUnitTokens
+	default:
+		return nil, fmt.Errorf("%w: unknown unit %q", ErrInvalidSizeLimit, match[2])
+	}
+
+	switch policy {
+	case OversizeSkip, OversizeHead, OversizeHeadTail:
+	default:
+		return nil, fmt.Errorf("%w: unknown policy %q", ErrInvalidSizeLimit, policy)
+	}
+
+	return &SizeLimit{
+		Max:    max * multiplier,
+		Unit:   unit,
+		Policy: policy,
+	}, nil
+}
+
+// measure returns the size of the content in the unit of the limit
+func (l *SizeLimit) measure(content []byte) int {
+	switch l.Unit {
+	case SizeUnitLines:
+		return countLines(content)
+	case SizeUnitTokens:
+		return estimateTokens(content)
+	default:
+		return len(content)
+	}
+}
+
+// mayExceed tells if a file of the size on disk may exceed the limit once decoded, so the files which cannot are judged without reading them
+func (l *SizeLimit) mayExceed(size int64) bool {
+	// Decoding can at most double the size of the content
+	upperBound := int(2 * size)
+	if l.Unit == SizeUnitTokens {
+		upperBound = (upperBound + bytesPerToken - 1) / bytesPerToken
+	}
+
+	return upperBound > l.Max
+}
+
+// blobSizeStatus tells if the transformed content of a file was cut or left out because of the size limit
+func blobSizeStatus(blob *cache.Blob) SizeStatus {
+	switch {
+	case blob.Skipped:
+		return SizeStatusSkipped
+	case slices.Contains(blob.Transformed, string(TransformMaxFileSize)):
+		return SizeStatusTruncated
+	default:
+		return ""
+	}
+}
+
+// keep returns how many lines from the head and the tail of the content fit in the limit, a negative head means the whole content fits
+func (l *SizeLimit) keep(content []byte) (head int, tail int, status SizeStatus) {
+	if l.measure(content) <= l.Max {
+		return -1, 0, ""
+	}
+
+	if l.Policy == OversizeSkip {
+		return 0, 0, SizeStatusSkipped
+	}
+
+	lines := splitLines(content)
+
+	budget := l.Max
+	if l.Policy == OversizeHeadTail {
+		budget = l.Max / 2
+	}
+
+	used := 0
+	for head < len(lines) && used+l.measure(lines[head]) <= budget {
+		used += l.measure(lines[head])
+		head++
+	}
+
+	if l.Policy == OversizeHeadTail {
+		for tail < len(lines)-head && used+l.measure(lines[len(lines)-1-tail]) <= l.Max {
+			used += l.measure(lines[len(lines)-1-tail])
+			tail++
+		}
+	}
+
+	return head, tail, SizeStatusTruncated
+}
+
+// ElidedLines returns the number of lines cut out of a truncated file if the line is the marker in their place, 0 otherwise
+func ElidedLines(line string) int {
+	match := elisionMarker.FindStringSubmatch(line)
+	if match == nil {
+		return 0
+	}
+
+	lines, _ := strconv.Atoi(match[1])
+	return lines
+}
+
+// elide keeps the first head and last tail lines of the content and replaces the rest with a marker
+func elide(content []byte, head int, tail int) []byte {
+	if head < 0 {
+		return content
+	}
+
+	lines := splitLines(content)
+
+	var elided bytes.Buffer
+	elided.Write(bytes.Join(lines[:head], nil))
+	fmt.Fprintf(&elided, "… [%d lines omitted] …\n", len(lines)-head-tail)
+	elided.Write(bytes.Join(lines[len(lines)-tail:], nil))
+
+	return elided.Bytes()
+}
@@ -0,0 +1,198 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hitesh22rana/sourcecollector/pkg/internal/testutil"
+)
+
+func TestParseSizeLimit(t *testing.T) {
+	tests := []struct {
+		value  string
+		policy OversizePolicy
+		want   *SizeLimit
+	}{
+		{"500", OversizeSkip, &SizeLimit{Max: 500, Unit: SizeUnitBytes, Policy: OversizeSkip}},
+		{"500b", OversizeSkip, &SizeLimit{Max: 500, Unit: SizeUnitBytes, Policy: OversizeSkip}},
+		{"64KB", OversizeHead, &SizeLimit{Max: 64 << 10, Unit: SizeUnitBytes, Policy: OversizeHead}},
+		{"1m", OversizeHead, &SizeLimit{Max: 1 << 20, Unit: SizeUnitBytes, Policy: OversizeHead}},
+		{"2GB", OversizeSkip, &SizeLimit{Max: 2 << 30, Unit: SizeUnitBytes, Policy: OversizeSkip}},
+		{"2000lines", OversizeHeadTail, &SizeLimit{Max: 2000, Unit: SizeUnitLines, Policy: OversizeHeadTail}},
+		{" 10 line ", OversizeHeadTail, &SizeLimit{Max: 10, Unit: SizeUnitLines, Policy: OversizeHeadTail}},
+		{"8000Tokens", OversizeSkip, &SizeLimit{Max: 8000, Unit: SizeUnitTokens, Policy: OversizeSkip}},
+	}
+
+	for _, test := range tests {
+		got, err := ParseSizeLimit(test.value, test.policy)
+		if err != nil {
+			t.Errorf("ParseSizeLimit(%q): %v", test.value, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ParseSizeLimit(%q) = %+v, want %+v", test.value, got, test.want)
+		}
+	}
+
+	for _, value := range []string{"", "-1", "1.5MB", "10 pages", "KB"} {
+		if _, err := ParseSizeLimit(value, OversizeSkip); !errors.Is(err, ErrInvalidSizeLimit) {
+			t.Errorf("ParseSizeLimit(%q): error %v, want %v", value, err, ErrInvalidSizeLimit)
+		}
+	}
+
+	if _, err := ParseSizeLimit("10lines", "truncate"); !errors.Is(err, ErrInvalidSizeLimit) {
+		t.Errorf("unknown policy: error %v, want %v", err, ErrInvalidSizeLimit)
+	}
+}
+
+func TestSizeLimitKeep(t *testing.T) {
+	// Ten lines of four bytes each
+	var lines []string
+	for i := 0; i < 10; i++ {
+		lines = append(lines, "ln"+string(rune('0'+i)))
+	}
+	content := []byte(strings.Join(lines, "\n") + "\n")
+
+	tests := []struct {
+		name  string
+		limit SizeLimit
+		head  int
+		tail  int
+		want  string
+	}{
+		{
+			name:  "fits",
+			limit: SizeLimit{Max: 10, Unit: SizeUnitLines, Policy: OversizeHead},
+			head:  -1,
+			want:  string(content),
+		},
+		{
+			name:  "head",
+			limit: SizeLimit{Max: 3, Unit: SizeUnitLines, Policy: OversizeHead},
+			head:  3,
+			want:  "ln0\nln1\nln2\n… [7 lines omitted] …\n",
+		},
+		{
+			name:  "head and tail",
+			limit: SizeLimit{Max: 4, Unit: SizeUnitLines, Policy: OversizeHeadTail},
+			head:  2,
+			tail:  2,
+			want:  "ln0\nln1\n… [6 lines omitted] …\nln8\nln9\n",
+		},
+		{
+			// Half of the budget goes to the head, the tail takes what the head left over
+			name:  "odd budget in bytes",
+			limit: SizeLimit{Max: 14, Unit: SizeUnitBytes, Policy: OversizeHeadTail},
+			head:  1,
+			tail:  2,
+			want:  "ln0\n… [7 lines omitted] …\nln8\nln9\n",
+		},
+		{
+			name:  "tokens",
+			limit: SizeLimit{Max: 2, Unit: SizeUnitTokens, Policy: OversizeHead},
+			head:  2,
+			want:  "ln0\nln1\n… [8 lines omitted] …\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			head, tail, status := test.limit.keep(content)
+			if head != test.head || tail != test.tail {
+				t.Errorf("keep = %d, %d, want %d, %d", head, tail, test.head, test.tail)
+			}
+			wantStatus := SizeStatusTruncated
+			if test.head < 0 {
+				wantStatus = ""
+			}
+			if status != wantStatus {
+				t.Errorf("status %q, want %q", status, wantStatus)
+			}
+
+			if got := string(elide(content, head, tail)); got != test.want {
+				t.Errorf("elide = %q, want %q", got, test.want)
+			}
+
+			// The marker tells how many lines were cut out, so the line numbers after it can be recovered
+			for _, line := range strings.Split(test.want, "\n") {
+				if elided := ElidedLines(line); elided > 0 && elided != 10-head-tail {
+					t.Errorf("marker %q counts %d lines, want %d", line, elided, 10-head-tail)
+				}
+			}
+		})
+	}
+
+	// The skip policy keeps nothing
+	limit := SizeLimit{Max: 3, Unit: SizeUnitLines, Policy: OversizeSkip}
+	if _, _, status := limit.keep(content); status != SizeStatusSkipped {
+		t.Errorf("skip policy status %q, want %q", status, SizeStatusSkipped)
+	}
+}
+
+func TestSizeStatus(t *testing.T) {
+	body := strings.Repeat("\tprintln()\n", 20)
+	files := map[string]string{
+		"small.txt": "small\n",
+		"big.txt":   strings.Repeat("line\n", 20),
+		"main.go":   "package main\n\nfunc main() {\n" + body + "}\n",
+	}
+
+	input := testutil.WriteFiles(t, files)
+	sc, err := NewSourceCollector(input, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc.Validator = testutil.AcceptAll{}
+	sc.Outline = true
+	sc.SizeLimit = &SizeLimit{Max: 6, Unit: SizeUnitLines, Policy: OversizeHeadTail}
+
+	ctx := context.Background()
+	sourceTree, err := sc.GenerateSourceTree(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The outline of main.go fits, only big.txt is cut
+	want := map[string]SizeStatus{
+		filepath.Join("input", "big.txt"): SizeStatusTruncated,
+	}
+
+	got := make(map[string]SizeStatus)
+	sc.forEachSourceFile(sourceTree, func(node SourceNode) {
+		if node.SizeStatus != "" {
+			relPath, _ := filepath.Rel(sc.BasePath, node.Path)
+			got[relPath] = node.SizeStatus
+		}
+	})
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("tree size statuses %v, want %v", got, want)
+	}
+
+	// The stats and a single file tell the same from the content they read
+	stats, err := sc.Stats(ctx, sourceTree)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got = make(map[string]SizeStatus)
+	for _, file := range stats.Files {
+		if file.SizeStatus != "" {
+			got[file.Path] = file.SizeStatus
+		}
+
+		read, err := sc.ReadSourceFile(file.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if read.SizeStatus != file.SizeStatus {
+			t.Errorf("%s: read with size status %q, stats %q", file.Path, read.SizeStatus, file.SizeStatus)
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("stats size statuses %v, want %v", got, want)
+	}
+}
@@ -2,14 +2,14 @@ package pkg
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
-	"log"
-	"os"
+	"io"
 	"path/filepath"
 	"runtime"
 	"strings"
-
-	"github.com/hitesh22rana/sourcecollector/pkg/validators"
+	"sync"
 )
 
 // NewSourceCollector creates a new SourceCollector
@@ -32,32 +32,19 @@ func NewSourceCollector(input string, output string, fast bool) (*SourceCollecto
 		return nil, ErrInvalidInputDirectory
 	}
 
-	// Validate if output file is a directory or don't have .txt extension
-	if !isValidPath(filepath.Dir(output)) || filepath.Ext(output) != ".txt" {
-		return nil, ErrInvalidOutputPath
-	}
-
-	if !filepath.IsAbs(output) {
-		output, err = filepath.Abs(output)
-		if err != nil {
+	// An empty output is allowed when the collection is only written with WriteSourceCode
+	if output != "" {
+		// Validate if output file is a directory or don't have .txt extension
+		if !isValidPath(filepath.Dir(output)) || filepath.Ext(output) != ".txt" {
 			return nil, ErrInvalidOutputPath
 		}
-	}
-
-	// Make the output file if it does not exist
-	outputFile, err := os.Create(output)
-	if err != nil {
-		return nil, ErrFailedToCreateFile
-	}
-	defer outputFile.Close()
-
-	// Make a new gitignore based validator
-	var validator validators.Validator
-	validator, err = validators.NewGitIgnoreBasedValidator(input)
 
-	// If the gitIgnoreBasedValidator is nil, then make a new default validator
-	if err != nil {
-		validator = validators.NewDefaultValidator()
+		if !filepath.IsAbs(output) {
+			output, err = filepath.Abs(output)
+			if err != nil {
+				return nil, ErrInvalidOutputPath
+			}
+		}
 	}
 
 	// If fast is true, then set maxConcurrency to max cpu cores available, else 1
@@ -70,15 +57,30 @@ func NewSourceCollector(input string, output string, fast bool) (*SourceCollecto
 		Input:          input,
 		Output:         output,
 		BasePath:       filepath.Dir(input),
-		Validator:      validator,
+		Validator:      newValidator(input),
 		MaxConcurrency: maxConcurrency,
+		OnError:        ErrorPolicySkip,
+		WriteMode:      WriteModeOverwrite,
+		Format:         FormatText,
+		Tree:           TreeOptions{Order: TreeOrderAlphabetical},
+		Symlinks:       SymlinkFollowWithinRoot,
 	}, nil
 }
 
-// GenerateSourceTree generates the source tree
-func (sc *SourceCollector) GenerateSourceTree() (*SourceTree, error) {
+// GenerateSourceTree generates the source tree, the walk stops as soon as the context is done
+func (sc *SourceCollector) GenerateSourceTree(ctx context.Context) (*SourceTree, error) {
+	// Forget the paths skipped by a previous walk
+	sc.skippedMu.Lock()
+	sc.skipped = nil
+	sc.skippedMu.Unlock()
+	sc.duplicates = nil
+
 	// Generate the source tree
-	sourceTree := sc.generateSourceTree(sc.Input)
+	sourceTree := sc.generateSourceTree(ctx, sc.Input)
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrSourceTreeGeneration, err)
+	}
+
 	if sourceTree == nil {
 		return nil, ErrSourceTreeGeneration
 	}
@@ -87,121 +89,272 @@ func (sc *SourceCollector) GenerateSourceTree() (*SourceTree, error) {
 }
 
 // GenerateSourceTreeStructure generates the source tree structure in string format
-func (sc *SourceCollector) GenerateSourceTreeStructure(sourceTree *SourceTree) (string, error) {
+func (sc *SourceCollector) GenerateSourceTreeStructure(ctx context.Context, sourceTree *SourceTree) (string, error) {
+	return sc.RenderSourceTree(ctx, sourceTree, TreeFormatText)
+}
+
+// RenderSourceTree renders the source tree structure in the format, as configured by the tree options of the collector
+func (sc *SourceCollector) RenderSourceTree(ctx context.Context, sourceTree *SourceTree, format TreeFormat) (string, error) {
+	var sb strings.Builder
+	if err := sc.WriteSourceTree(ctx, &sb, sourceTree, format); err != nil {
+		return "", err
+	}
+
+	return sb.String(), nil
+}
+
+// WriteSourceTree streams the source tree structure in the format to w in a single pass, as configured by the tree options of the collector
+func (sc *SourceCollector) WriteSourceTree(ctx context.Context, w io.Writer, sourceTree *SourceTree, format TreeFormat) error {
 	// Check if the sourceTree is nil
 	if sourceTree == nil {
-		return "", ErrSourceTreeStructure
+		return ErrSourceTreeStructure
 	}
 
-	// Generate the tree structure
-	sourceTreeStructure := sc.generateSourceTreeStructure(sourceTree, 0)
-	if sourceTreeStructure == "" {
-		return "", ErrSourceTreeStructure
+	// Check if the collection is already cancelled
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%w: %w", ErrSourceTreeStructure, err)
 	}
 
-	return sourceTreeStructure, nil
+	renderer := newTreeRenderer(sc.Tree, sc.BasePath, sc.Output)
+	renderer.paths = format == TreeFormatJSON
+
+	// Measure the files for the annotations, the files which cannot be read are shown without them
+	if len(sc.Tree.Annotations) > 0 {
+		stats, err := sc.Stats(ctx, sourceTree)
+		if err != nil && !isCollectionError(err) {
+			return fmt.Errorf("%w: %w", ErrSourceTreeStructure, err)
+		}
+
+		renderer.measure(sourceTree, sc.statsByPath(stats))
+	}
+
+	// Find the duplicates before they are written, unless the files were already written
+	if sc.Dedupe {
+		if sc.duplicates == nil {
+			if err := sc.indexDuplicates(ctx, sourceTree); err != nil {
+				return fmt.Errorf("%w: %w", ErrSourceTreeStructure, err)
+			}
+		}
+
+		renderer.duplicates = sc.duplicates
+	}
+
+	if sc.Tree.ShowExcluded {
+		renderer.addExcluded(sc.SkippedPaths())
+	}
+
+	// Write the tree structure through a buffer, the writer remembers the first write error
+	out := bufio.NewWriter(w)
+	renderer.render(newTreeWriter(format, out, sc.Tree), sourceTree)
+
+	if err := out.Flush(); err != nil {
+		return fmt.Errorf("%w: %v", ErrSourceTreeStructure, err)
+	}
+
+	return nil
 }
 
-// SaveSourceCode saves the source tree to the output path
-func (sc *SourceCollector) SaveSourceCode(sourceTree *SourceTree, sourceTreeStructure string) error {
+// SaveSourceCode saves the source tree to the output path, the files which could not be collected are returned as a *CollectionError.
+// The output is written to a temporary file which replaces the output path only if the collection succeeds, so a failed or cancelled run keeps the previous output.
+func (sc *SourceCollector) SaveSourceCode(ctx context.Context, sourceTree *SourceTree, sourceTreeStructure string) error {
 	// Check if the source tree is nil
 	if sourceTree == nil {
 		return ErrSaveSourceTree
 	}
 
-	// Open the output file in append mode
-	file, err := os.OpenFile(sc.Output, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	// Check if there is an output to save to
+	if sc.Output == "" {
+		return ErrInvalidOutputPath
+	}
+
+	// Create the temporary output file only now, so nothing is written to disk before the collection starts
+	file, err := sc.createOutputFile()
 	if err != nil {
-		return ErrOpenOutputFile
+		return err
 	}
-	defer file.Close()
+	defer file.discard()
 
-	// If sourceTreeStructure is not provided, then skip saving the source tree structure else, add the source code files tree structure to the output file and save it
-	if sourceTreeStructure != "" {
-		if _, err := file.Write([]byte("Source code files structure\n\n" + sourceTreeStructure + "\n\n")); err != nil {
-			return ErrWriteOutputFile
+	// Keep the previous output if the collection was cancelled or failed
+	var collectionErr *CollectionError
+	if err := sc.WriteSourceCode(ctx, file, sourceTree, sourceTreeStructure); err != nil {
+		if !errors.As(err, &collectionErr) || sc.OnError == ErrorPolicyFail {
+			return err
 		}
 	}
 
-	// Make a data channel to save the source code files
-	dataChan := make(chan string)
+	// Replace the output with the complete collection
+	if err := file.commit(); err != nil {
+		return err
+	}
 
-	// Done channel to wait for the goroutine to finish
-	done := make(chan bool)
+	if collectionErr != nil {
+		return collectionErr
+	}
 
-	// Save the source code files, pick the data from the data channel and save it to the output file
-	go func(dataChan chan string) {
-		// Wait channel for the goroutine to finish of size equal to the sc.MaxConcurrency if order is not required else fallback to 1
-		waitChan := make(chan bool, sc.MaxConcurrency)
+	return nil
+}
 
-		// Make multiple goroutines to write the data concurrently
-		for i := 0; i < sc.MaxConcurrency; i++ {
-			go func(dataChan chan string) {
-				for data := range dataChan {
-					if _, err := file.WriteString(data); err != nil {
-						log.Println("failed to write output file", err)
-					}
-				}
+// WriteSourceCode writes the source tree to w in the format of the collector, the files which could not be collected are returned as a *CollectionError.
+// If the context is done before the collection finishes, the context error is returned and w holds a partial collection.
+func (sc *SourceCollector) WriteSourceCode(ctx context.Context, w io.Writer, sourceTree *SourceTree, sourceTreeStructure string) error {
+	// Check if the source tree is nil
+	if sourceTree == nil {
+		return ErrSaveSourceTree
+	}
 
-				// Signal the wait channel
-				waitChan <- true
-			}(dataChan)
+	formatter := sc.formatter()
+
+	// Add the source code files tree structure before the files, an empty structure is left out
+	if _, err := io.WriteString(w, formatter.header(sourceTreeStructure)); err != nil {
+		return fmt.Errorf("%w: %v", ErrWriteOutputFile, err)
+	}
+
+	err := sc.writeSourceFiles(ctx, w, formatter, func(ctx context.Context, emit func(node SourceNode)) {
+		sc.forEachSourceFile(sourceTree, emit)
+	})
+
+	// Close the collection, unless it was aborted
+	if err == nil || (isCollectionError(err) && sc.OnError != ErrorPolicyFail) {
+		if _, err := io.WriteString(w, formatter.footer("")); err != nil {
+			return fmt.Errorf("%w: %v", ErrWriteOutputFile, err)
 		}
+	}
 
-		// Wait for the goroutines to finish
-		for i := 0; i < sc.MaxConcurrency; i++ {
-			<-waitChan
+	return err
+}
+
+// OmittedPaths returns the files left out of the last collection because they did not fit in MaxTokens, relative to the base path in the order of the output
+func (sc *SourceCollector) OmittedPaths() []string {
+	return append([]string{}, sc.omitted...)
+}
+
+// writeSourceFiles reads the files produced by produce with sc.MaxConcurrency goroutines and writes them to w, the files which could not be collected are returned as a *CollectionError.
+// produce must stop emitting once its context is done, emit is safe for concurrent use.
+func (sc *SourceCollector) writeSourceFiles(ctx context.Context, w io.Writer, formatter formatter, produce func(ctx context.Context, emit func(node SourceNode))) error {
+	// Abort the collection on cancellation or on the first error which should not be skipped
+	parentCtx := ctx
+	ctx, abort := context.WithCancel(ctx)
+	defer abort()
+
+	// Collect the errors of the files which could not be read
+	var (
+		fileErrorsMu sync.Mutex
+		fileErrors   []*FileError
+	)
+
+	// Index the files as they are written, unless the tree already did
+	if sc.Dedupe && sc.duplicates == nil {
+		sc.duplicates = newDuplicateIndex()
+	}
+
+	// Write the files in the order they were produced when the duplicates are referred to or the budget is spent, so the references match the duplicates marked in the tree and the same files fit every time.
+	// Otherwise every file is written as soon as it is read.
+	ordered := sc.Dedupe || sc.MaxTokens > 0
+	sc.omitted = nil
+
+	// Make a data channel to save the source code files, a file without a node only moves the order on
+	dataChan := make(chan *processedFile)
+
+	// Done channel to wait for the writer to finish, it receives the write error if any
+	done := make(chan error)
+
+	// Save the source code files, pick the data from the data channel and write it to the output
+	go func(dataChan chan *processedFile) {
+		var writeErr error
+		first := true
+		tokens := 0
+		write := func(file *processedFile) {
+			// Keep draining the data channel after a failed write or an abort, so the workers are not blocked
+			if writeErr != nil || ctx.Err() != nil || file.relPath == "" {
+				return
+			}
+
+			// The first file written with a content is written in full, the later ones refer to it without using the budget
+			var data string
+			if original := sc.duplicateOf(file); original != "" {
+				data = formatter.duplicate(file.node.Name, file.relPath, original)
+			} else {
+				if sc.MaxTokens > 0 && tokens+file.tokens > sc.MaxTokens {
+					sc.omitted = append(sc.omitted, file.relPath)
+					return
+				}
+				tokens += file.tokens
+
+				sc.wroteSourceFile(file)
+				data = formatter.file(file.node.Name, file.relPath, file.data, file.transformed)
+			}
+
+			if !first {
+				data = formatter.separator() + data
+			}
+			first = false
+
+			if _, err := io.WriteString(w, data); err != nil {
+				writeErr = fmt.Errorf("%w: %v", ErrWriteOutputFile, err)
+				abort()
+			}
 		}
 
-		// Close the waitChan channel
-		close(waitChan)
+		// The files read ahead of their turn wait until the files before them are written
+		pending := make(map[int]*processedFile)
+		next := 0
+		for file := range dataChan {
+			if !ordered {
+				write(file)
+				continue
+			}
+
+			pending[file.seq] = file
+			for file, ok := pending[next]; ok; file, ok = pending[next] {
+				delete(pending, next)
+				next++
+				write(file)
+			}
+		}
 
 		// Signal the done channel
-		done <- true
+		done <- writeErr
 	}(dataChan)
 
-	// Make a queue channel which takes the SourceNode as input and send the source code data to the data channel
-	queueChan := make(chan SourceNode)
+	// Make a queue channel which takes the SourceNode with its position as input and send the source code data to the data channel
+	queueChan := make(chan queuedFile)
 
 	// Process the source code files from the queue channel and send the data to the data channel
-	go func(queueChan chan SourceNode, dataChan chan string) {
+	go func(queueChan chan queuedFile, dataChan chan *processedFile) {
 		// Wait channel for the goroutine to finish of size equal to the sc.MaxConcurrency
 		waitChan := make(chan bool, sc.MaxConcurrency)
 
 		// Make multiple goroutines to read and process the source code files concurrently
 		for i := 0; i < sc.MaxConcurrency; i++ {
-			go func(queueChan chan SourceNode) {
+			go func(queueChan chan queuedFile) {
 				for queueData := range queueChan {
-					name := queueData.Name
-					path := queueData.Path
-
-					// Use bufio for efficient file reading
-					file, err := os.Open(path)
-					if err != nil {
-						log.Fatalln("failed to open file", err)
+					// Drain the queue without reading once the collection is aborted
+					if ctx.Err() != nil {
+						continue
 					}
 
-					// Get the relative path of the file
-					relPath, _ := filepath.Rel(sc.BasePath, path)
-
-					var sb strings.Builder
+					file, err := sc.processSourceFile(queueData.node)
+					if err != nil {
+						fileErrorsMu.Lock()
+						fileErrors = append(fileErrors, err)
+						fileErrorsMu.Unlock()
 
-					sb.WriteString("Name: " + name + "\nPath: " + relPath + "\n```\n")
-					scanner := bufio.NewScanner(file)
-					for scanner.Scan() {
-						sb.Write(scanner.Bytes())
-						sb.WriteString("\n")
+						if sc.OnError == ErrorPolicyFail {
+							abort()
+						}
 					}
-					file.Close()
 
-					if err := scanner.Err(); err != nil {
-						log.Fatalln("failed to read file", err)
+					// The files which could not be read or were left out because of the size limit are not written, but still take their turn
+					if file == nil {
+						file = &processedFile{}
 					}
-
-					sb.WriteString("\n```\n\n")
+					file.seq = queueData.seq
 
 					// Add the file content to the data channel
-					dataChan <- sb.String()
+					select {
+					case dataChan <- file:
+					case <-ctx.Done():
+					}
 				}
 
 				// Signal the wait channel
@@ -221,44 +374,43 @@ func (sc *SourceCollector) SaveSourceCode(sourceTree *SourceTree, sourceTreeStru
 		close(dataChan)
 	}(queueChan, dataChan)
 
-	queue := []*SourceTree{sourceTree}
-	for len(queue) > 0 {
-		node := queue[0]
-		queue = queue[1:]
-
-		// Check if the node is nil
-		if node == nil {
-			continue
-		}
-
-		for _, node := range node.Nodes {
-			// Check if the node is nil or the node is the output path
-			if node == nil || node.Root.Path == sc.Output {
-				continue
-			}
-
-			// Check if the node is a directory or a file, if it is a directory, add it to the queue and continue
-			if node.Nodes != nil {
-				queue = append(queue, node)
-				continue
-			}
-
-			// Add the file path to the queue channel
-			queueChan <- SourceNode{
-				Name: node.Root.Name,
-				Path: node.Root.Path,
-			}
+	// Add the file paths to the queue channel in the order they are produced, until the collection is aborted
+	var (
+		seqMu sync.Mutex
+		seq   int
+	)
+	produce(ctx, func(node SourceNode) {
+		seqMu.Lock()
+		queued := queuedFile{node: node, seq: seq}
+		seq++
+		seqMu.Unlock()
+
+		select {
+		case queueChan <- queued:
+		case <-ctx.Done():
 		}
-	}
+	})
 
 	// Close the queue channel
 	close(queueChan)
 
-	// Wait for the goroutine to finish
-	<-done
+	// Wait for the writer to finish
+	writeErr := <-done
 
 	// Close the done channel
 	close(done)
 
+	if err := parentCtx.Err(); err != nil {
+		return fmt.Errorf("%w: %w", ErrSaveSourceTree, err)
+	}
+
+	if writeErr != nil {
+		return writeErr
+	}
+
+	if len(fileErrors) > 0 {
+		return newCollectionError(fileErrors)
+	}
+
 	return nil
 }
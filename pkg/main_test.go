@@ -0,0 +1,238 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hitesh22rana/sourcecollector/pkg/validators"
+)
+
+// unreadableFiles are collected with two of them removed after the walk
+var unreadableFiles = map[string]string{
+	"a.go":     "package a\n",
+	"b/b.go":   "package b\n",
+	"c.go":     "package c\n",
+	"d/d/d.go": "package d\n",
+}
+
+// removeFiles returns a function which removes the files of the input of the collector
+func removeFiles(t *testing.T, sc *SourceCollector, paths ...string) func() {
+	return func() {
+		for _, path := range paths {
+			if err := os.Remove(filepath.Join(sc.Input, filepath.FromSlash(path))); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestSaveSourceCodeOnErrorSkip(t *testing.T) {
+	sc := newOutputCollector(t, unreadableFiles)
+	sc.MaxConcurrency = 4
+
+	err := saveSourceCode(t, context.Background(), sc, removeFiles(t, sc, "d/d/d.go", "b/b.go"))
+
+	// The failed files are sorted by path, whatever order the workers hit them in
+	var collectionErr *CollectionError
+	if !errors.As(err, &collectionErr) {
+		t.Fatalf("error %v, want a collection error", err)
+	}
+
+	var failed []string
+	for _, fileErr := range collectionErr.Errors {
+		failed = append(failed, fileErr.Path)
+	}
+	want := []string{filepath.Join("input", "b", "b.go"), filepath.Join("input", "d", "d", "d.go")}
+	if !reflect.DeepEqual(failed, want) {
+		t.Errorf("failed files %v, want %v", failed, want)
+	}
+
+	// The other files are committed to the output
+	data, err := os.ReadFile(sc.Output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bundle, err := ParseBundle(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var written []string
+	for _, file := range bundle {
+		written = append(written, file.Path)
+	}
+	sort.Strings(written)
+
+	want = []string{filepath.Join("input", "a.go"), filepath.Join("input", "c.go")}
+	if !reflect.DeepEqual(written, want) {
+		t.Errorf("written files %v, want %v", written, want)
+	}
+}
+
+func TestSaveSourceCodeOnErrorFail(t *testing.T) {
+	const previous = "previous collection\n"
+
+	sc := newOutputCollector(t, unreadableFiles)
+	sc.MaxConcurrency = 4
+	sc.OnError = ErrorPolicyFail
+	if err := os.WriteFile(sc.Output, []byte(previous), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := saveSourceCode(t, context.Background(), sc, removeFiles(t, sc, "b/b.go"))
+
+	var collectionErr *CollectionError
+	if !errors.As(err, &collectionErr) || len(collectionErr.Errors) != 1 {
+		t.Fatalf("error %v, want the collection error of input/b/b.go", err)
+	}
+
+	assertOutput(t, sc, previous)
+}
+
+// cancelOnWalk is a validator which cancels the collection when the walk reaches its first path
+type cancelOnWalk struct {
+	cancel context.CancelFunc
+
+	// walked counts the paths the walk reached
+	walked *atomic.Int32
+}
+
+func (v cancelOnWalk) IsIgnored(string) (bool, validators.Reason) {
+	v.walked.Add(1)
+	v.cancel()
+	return false, validators.Reason{}
+}
+
+// cancelOnWrite is a writer which cancels the collection on its second write, the first file after the header
+type cancelOnWrite struct {
+	out    strings.Builder
+	cancel context.CancelFunc
+	writes int
+}
+
+func (w *cancelOnWrite) Write(p []byte) (int, error) {
+	if w.writes++; w.writes == 2 {
+		w.cancel()
+	}
+	return w.out.Write(p)
+}
+
+// manyFiles returns n files spread over a few directories
+func manyFiles(n int) map[string]string {
+	files := make(map[string]string, n)
+	for i := 0; i < n; i++ {
+		files[fmt.Sprintf("dir%d/file%d.go", i%5, i)] = "package dir\n"
+	}
+	return files
+}
+
+func TestCancelStopsWalk(t *testing.T) {
+	sc := newOutputCollector(t, manyFiles(100))
+	sc.MaxConcurrency = 4
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	walked := &atomic.Int32{}
+	sc.Validator = cancelOnWalk{cancel: cancel, walked: walked}
+
+	if _, err := sc.GenerateSourceTree(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("error %v, want %v", err, context.Canceled)
+	}
+
+	// Every directory reads its entries before it checks the context, but none goes deeper
+	if n := walked.Load(); n > 5 {
+		t.Errorf("the walk went on to %d paths after it was cancelled", n)
+	}
+}
+
+func TestCancelStopsWorkers(t *testing.T) {
+	sc := newOutputCollector(t, manyFiles(100))
+	sc.MaxConcurrency = 4
+
+	sourceTree, err := sc.GenerateSourceTree(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	out := &cancelOnWrite{cancel: cancel}
+	if err := sc.WriteSourceCode(ctx, out, sourceTree, ""); !errors.Is(err, context.Canceled) {
+		t.Fatalf("error %v, want %v", err, context.Canceled)
+	}
+
+	// Nothing is written after the file which cancelled the collection
+	if n := strings.Count(out.out.String(), "Name: "); n != 1 {
+		t.Errorf("%d files written, want only the one before the cancellation", n)
+	}
+}
+
+func TestCancelLeavesNoOutput(t *testing.T) {
+	sc := newOutputCollector(t, manyFiles(20))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := saveSourceCode(t, ctx, sc, nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("error %v, want %v", err, context.Canceled)
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(sc.Output))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if entry.Name() != "input" {
+			t.Errorf("%s was left next to the input", entry.Name())
+		}
+	}
+}
+
+func TestWriteSourceCodeMaxTokens(t *testing.T) {
+	files := map[string]string{
+		"a/stub.go": "package stub\n",
+		"b/big.go":  "package big\n\n// " + strings.Repeat("long comment ", 20) + "\n",
+		"c/stub.go": "package stub\n",
+		"d/main.go": "package main\n",
+	}
+
+	// The budget is spent in the order of the output, however many files are read at once
+	for run := 0; run < 5; run++ {
+		var sc *SourceCollector
+		out := collect(t, files, FormatText, func(collector *SourceCollector) {
+			sc = collector
+			sc.Dedupe = true
+			sc.MaxTokens = 8
+		})
+
+		bundle, err := ParseBundle(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The duplicate refers to the stub without using the budget, main.go still fits after big.go is left out
+		var written []string
+		for _, file := range bundle {
+			written = append(written, file.Path)
+		}
+		want := []string{filepath.Join("input", "a", "stub.go"), filepath.Join("input", "c", "stub.go"), filepath.Join("input", "d", "main.go")}
+		if !reflect.DeepEqual(written, want) {
+			t.Errorf("written files %v, want %v", written, want)
+		}
+
+		if omitted, want := sc.OmittedPaths(), []string{filepath.Join("input", "b", "big.go")}; !reflect.DeepEqual(omitted, want) {
+			t.Errorf("omitted files %v, want %v", omitted, want)
+		}
+	}
+}
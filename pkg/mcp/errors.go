@@ -0,0 +1,10 @@
+package mcp
+
+import "errors"
+
+var (
+	ErrUnknownTool  = errors.New("unknown tool")
+	ErrInvalidArgs  = errors.New("invalid tool arguments")
+	ErrReadMessage  = errors.New("failed to read message")
+	ErrWriteMessage = errors.New("failed to write message")
+)
@@ -0,0 +1,227 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	sourcecollector "github.com/hitesh22rana/sourcecollector/pkg"
+)
+
+// protocolVersion is the latest version of the Model Context Protocol the server speaks
+const protocolVersion = "2025-03-26"
+
+// supportedProtocolVersions are the versions the server accepts from a client
+var supportedProtocolVersions = map[string]struct{}{
+	"2024-11-05": {},
+	"2025-03-26": {},
+	"2025-06-18": {},
+}
+
+// JSON-RPC error codes
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// maxMessageSize is the largest message read from the client
+const maxMessageSize = 16 << 20
+
+// Server is a Model Context Protocol server exposing the collection of a directory as tools
+type Server struct {
+	// Name of the server reported to the client
+	Name string
+
+	// Version of the server reported to the client
+	Version string
+
+	// NewCollector makes the collector of a tool call, every call gets its own so the redaction findings are not shared
+	NewCollector func() (*sourcecollector.SourceCollector, error)
+
+	// writeMu serializes the messages written to the client
+	writeMu sync.Mutex
+
+	// callsMu guards calls
+	callsMu sync.Mutex
+
+	// calls cancels the running requests by their id
+	calls map[string]context.CancelFunc
+}
+
+// request is a JSON-RPC request or notification, notifications have no id
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// response is a JSON-RPC response
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *responseError  `json:"error,omitempty"`
+}
+
+// responseError is the error of a JSON-RPC response
+type responseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Serve reads newline delimited JSON-RPC messages from r and writes the responses to w, until r is closed or the context is done.
+// Requests are handled concurrently and can be cancelled by the client.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	s.calls = make(map[string]context.CancelFunc)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxMessageSize)
+
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var req request
+		if err := json.Unmarshal(line, &req); err != nil {
+			s.write(w, &response{ID: json.RawMessage("null"), Error: &responseError{Code: codeParseError, Message: err.Error()}})
+			continue
+		}
+
+		// Notifications are never answered
+		if len(req.ID) == 0 {
+			s.handleNotification(&req)
+			continue
+		}
+
+		callCtx, cancelCall := context.WithCancel(ctx)
+		s.callsMu.Lock()
+		s.calls[string(req.ID)] = cancelCall
+		s.callsMu.Unlock()
+
+		wg.Add(1)
+		go func(req request) {
+			defer wg.Done()
+			defer func() {
+				s.callsMu.Lock()
+				delete(s.calls, string(req.ID))
+				s.callsMu.Unlock()
+				cancelCall()
+			}()
+
+			result, rpcErr := s.handleRequest(callCtx, &req)
+
+			// A cancelled request is not answered
+			if callCtx.Err() != nil {
+				return
+			}
+
+			s.write(w, &response{ID: req.ID, Result: result, Error: rpcErr})
+		}(req)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("%w: %v", ErrReadMessage, err)
+	}
+
+	return nil
+}
+
+// handleNotification handles a message which expects no response
+func (s *Server) handleNotification(req *request) {
+	if req.Method != "notifications/cancelled" {
+		return
+	}
+
+	var params struct {
+		RequestID json.RawMessage `json:"requestId"`
+	}
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return
+	}
+
+	s.callsMu.Lock()
+	if cancel, ok := s.calls[string(params.RequestID)]; ok {
+		cancel()
+	}
+	s.callsMu.Unlock()
+}
+
+// handleRequest returns the result of a request or its error
+func (s *Server) handleRequest(ctx context.Context, req *request) (any, *responseError) {
+	if req.JSONRPC != "2.0" {
+		return nil, &responseError{Code: codeInvalidRequest, Message: "jsonrpc must be 2.0"}
+	}
+
+	switch req.Method {
+	case "initialize":
+		var params struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		json.Unmarshal(req.Params, &params)
+
+		// Agree on the version of the client if it is supported, else offer the latest one
+		version := protocolVersion
+		if _, ok := supportedProtocolVersions[params.ProtocolVersion]; ok {
+			version = params.ProtocolVersion
+		}
+
+		return map[string]any{
+			"protocolVersion": version,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]string{"name": s.Name, "version": s.Version},
+		}, nil
+
+	case "ping":
+		return map[string]any{}, nil
+
+	case "tools/list":
+		return map[string]any{"tools": toolDefinitions}, nil
+
+	case "tools/call":
+		var params struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &responseError{Code: codeInvalidParams, Message: err.Error()}
+		}
+
+		return s.callTool(ctx, params.Name, params.Arguments), nil
+
+	default:
+		return nil, &responseError{Code: codeMethodNotFound, Message: "method not found: " + req.Method}
+	}
+}
+
+// write writes a single message to the client
+func (s *Server) write(w io.Writer, resp *response) {
+	resp.JSONRPC = "2.0"
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		data, _ = json.Marshal(&response{JSONRPC: "2.0", ID: resp.ID, Error: &responseError{Code: codeInvalidRequest, Message: fmt.Sprintf("%v: %v", ErrWriteMessage, err)}})
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	w.Write(append(data, '\n'))
+}
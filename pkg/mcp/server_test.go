@@ -0,0 +1,352 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	sourcecollector "github.com/hitesh22rana/sourcecollector/pkg"
+	"github.com/hitesh22rana/sourcecollector/pkg/internal/testutil"
+)
+
+// newTestServer creates a server of an input directory with the files, the options are applied to the collector of every call
+func newTestServer(t *testing.T, files map[string]string, options ...func(sc *sourcecollector.SourceCollector)) *Server {
+	t.Helper()
+
+	input := testutil.WriteFiles(t, files)
+
+	return &Server{
+		Name:    "sourcecollector",
+		Version: "test",
+		NewCollector: func() (*sourcecollector.SourceCollector, error) {
+			sc, err := sourcecollector.NewSourceCollector(input, "", false)
+			if err != nil {
+				return nil, err
+			}
+
+			sc.Validator = testutil.AcceptAll{}
+			for _, option := range options {
+				option(sc)
+			}
+
+			return sc, nil
+		},
+	}
+}
+
+// testResponse is a response as the client reads it
+type testResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result"`
+	Error   *responseError  `json:"error"`
+}
+
+// roundTrip sends the messages to the server and returns the responses by their id
+func roundTrip(t *testing.T, s *Server, messages ...string) map[string]testResponse {
+	t.Helper()
+
+	var out bytes.Buffer
+	if err := s.Serve(context.Background(), strings.NewReader(strings.Join(messages, "\n")+"\n"), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	responses := make(map[string]testResponse)
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		if line == "" {
+			continue
+		}
+
+		var resp testResponse
+		if err := json.Unmarshal([]byte(line), &resp); err != nil {
+			t.Fatalf("invalid response %q: %v", line, err)
+		}
+		if resp.JSONRPC != "2.0" {
+			t.Errorf("response %s has jsonrpc %q", line, resp.JSONRPC)
+		}
+
+		responses[string(resp.ID)] = resp
+	}
+
+	return responses
+}
+
+// callTool returns the text of the result of a tool call and whether it is an error
+func callTool(t *testing.T, s *Server, name string, arguments string) (string, bool) {
+	t.Helper()
+
+	texts, isError := callToolContent(t, s, name, arguments)
+	if len(texts) != 1 {
+		t.Fatalf("%s result has %d text blocks, want a single one", name, len(texts))
+	}
+
+	return texts[0], isError
+}
+
+// callToolContent calls the tool and returns the text of every block of its result
+func callToolContent(t *testing.T, s *Server, name string, arguments string) ([]string, bool) {
+	t.Helper()
+
+	message := fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":%q,"arguments":%s}}`, name, arguments)
+	resp, ok := roundTrip(t, s, message)["1"]
+	if !ok {
+		t.Fatalf("no response to the %s call", name)
+	}
+	if resp.Error != nil {
+		t.Fatalf("%s call failed: %+v", name, resp.Error)
+	}
+
+	var result toolResult
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		t.Fatal(err)
+	}
+
+	texts := make([]string, len(result.Content))
+	for i, content := range result.Content {
+		if content.Type != "text" {
+			t.Fatalf("%s result has content %+v, want text blocks", name, result.Content)
+		}
+		texts[i] = content.Text
+	}
+
+	return texts, result.IsError
+}
+
+func TestInitialize(t *testing.T) {
+	s := newTestServer(t, nil)
+
+	responses := roundTrip(t, s,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"test","version":"1"}}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":"two","method":"initialize","params":{"protocolVersion":"1999-01-01"}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"ping"}`,
+		`{"jsonrpc":"2.0","id":4,"method":"resources/list"}`,
+		`{"jsonrpc":"1.0","id":5,"method":"ping"}`,
+		`not json`,
+	)
+
+	// The notification is not answered
+	if len(responses) != 6 {
+		t.Errorf("got %d responses, want 6: %v", len(responses), responses)
+	}
+
+	var result struct {
+		ProtocolVersion string            `json:"protocolVersion"`
+		Capabilities    map[string]any    `json:"capabilities"`
+		ServerInfo      map[string]string `json:"serverInfo"`
+	}
+	if err := json.Unmarshal(responses["1"].Result, &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.ProtocolVersion != "2024-11-05" {
+		t.Errorf("protocol version %q, want the supported version of the client", result.ProtocolVersion)
+	}
+	if _, ok := result.Capabilities["tools"]; !ok {
+		t.Errorf("capabilities %v do not include tools", result.Capabilities)
+	}
+	if result.ServerInfo["name"] != "sourcecollector" || result.ServerInfo["version"] != "test" {
+		t.Errorf("server info %v", result.ServerInfo)
+	}
+
+	if err := json.Unmarshal(responses[`"two"`].Result, &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.ProtocolVersion != protocolVersion {
+		t.Errorf("protocol version %q for an unsupported client version, want %q", result.ProtocolVersion, protocolVersion)
+	}
+
+	if resp := responses["3"]; resp.Error != nil || string(resp.Result) != "{}" {
+		t.Errorf("ping response %+v", resp)
+	}
+
+	errorCodes := map[string]int{"4": codeMethodNotFound, "5": codeInvalidRequest, "null": codeParseError}
+	for id, code := range errorCodes {
+		if resp := responses[id]; resp.Error == nil || resp.Error.Code != code {
+			t.Errorf("response %s has error %+v, want code %d", id, resp.Error, code)
+		}
+	}
+}
+
+func TestToolsList(t *testing.T) {
+	s := newTestServer(t, nil)
+
+	resp := roundTrip(t, s, `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)["1"]
+
+	var result struct {
+		Tools []tool `json:"tools"`
+	}
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, tool := range result.Tools {
+		names = append(names, tool.Name)
+		if tool.Description == "" || tool.InputSchema["type"] != "object" {
+			t.Errorf("tool %s has no description or object schema", tool.Name)
+		}
+	}
+
+	if got, want := strings.Join(names, ","), "list_tree,read_files,collect,search"; got != want {
+		t.Errorf("tools %s, want %s", got, want)
+	}
+}
+
+func TestListTree(t *testing.T) {
+	s := newTestServer(t, map[string]string{
+		"main.go":        "package main\n",
+		"docs/README.md": "# Docs\n",
+	})
+
+	text, isError := callTool(t, s, "list_tree", `{}`)
+	if isError {
+		t.Fatal(text)
+	}
+	for _, path := range []string{"input/main.go", "input/docs/README.md"} {
+		if !strings.Contains(text, "\n"+path+"\n") {
+			t.Errorf("%s is not listed:\n%s", path, text)
+		}
+	}
+
+	text, _ = callTool(t, s, "list_tree", `{"exclude":["docs/**"]}`)
+	if strings.Contains(text, "README.md") {
+		t.Errorf("the excluded file is listed:\n%s", text)
+	}
+}
+
+func TestReadFiles(t *testing.T) {
+	s := newTestServer(t, map[string]string{"main.go": "package main\n"})
+
+	text, isError := callTool(t, s, "read_files", `{"paths":["input/main.go","input/missing.go","../outside.go"]}`)
+	if isError {
+		t.Fatal(text)
+	}
+
+	if !strings.Contains(text, "Path: input/main.go") || !strings.Contains(text, "package main") {
+		t.Errorf("main.go is missing:\n%s", text)
+	}
+	if strings.Count(text, "Error: ") != 2 {
+		t.Errorf("the missing and outside paths are not reported:\n%s", text)
+	}
+
+	if text, isError := callTool(t, s, "read_files", `{}`); !isError || !strings.Contains(text, ErrInvalidArgs.Error()) {
+		t.Errorf("read_files without paths returned %q", text)
+	}
+}
+
+func TestCollectMaxTokens(t *testing.T) {
+	s := newTestServer(t, map[string]string{
+		"a.go": "package a\n",
+		"b.go": "package b\n\n// " + strings.Repeat("long comment ", 20) + "\n",
+		"c.go": "package c\n",
+	})
+
+	texts, isError := callToolContent(t, s, "collect", `{"max_tokens":10}`)
+	if isError || len(texts) != 2 {
+		t.Fatalf("collect returned %q, want the collection and its notes", texts)
+	}
+	text, notes := texts[0], texts[1]
+
+	// The files after the one over the budget are still collected if they fit
+	for _, path := range []string{"input/a.go", "input/c.go"} {
+		if !strings.Contains(text, "Path: "+path) {
+			t.Errorf("%s is missing:\n%s", path, text)
+		}
+	}
+	if strings.Contains(text, "Path: input/b.go") {
+		t.Errorf("b.go over the budget is collected:\n%s", text)
+	}
+	if notes != "Omitted 1 file(s) over the budget of 10 tokens:\ninput/b.go\n" {
+		t.Errorf("b.go is not listed as omitted:\n%s", notes)
+	}
+
+	text, _ = callTool(t, s, "collect", `{}`)
+	if !strings.Contains(text, "Path: input/b.go") || strings.Contains(text, "Omitted") {
+		t.Errorf("without a budget every file is collected:\n%s", text)
+	}
+}
+
+func TestCollectFormat(t *testing.T) {
+	files := map[string]string{
+		"a/stub.go": "package stub\n",
+		"b/stub.go": "package stub\n",
+		"main.go":   "package main\n",
+	}
+
+	s := newTestServer(t, files, func(sc *sourcecollector.SourceCollector) {
+		sc.Format = sourcecollector.FormatJSON
+		sc.Dedupe = true
+	})
+
+	text, isError := callTool(t, s, "collect", `{}`)
+	if isError {
+		t.Fatal(text)
+	}
+
+	// The collection is a single JSON document with the duplicate written as a reference
+	if !json.Valid([]byte(text)) {
+		t.Fatalf("collect returned invalid JSON:\n%s", text)
+	}
+
+	bundle, err := sourcecollector.ParseBundle([]byte(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bundle) != len(files) {
+		t.Fatalf("parsed %d files, want %d", len(bundle), len(files))
+	}
+	for _, file := range bundle {
+		if file.Path == filepath.Join("input", "b", "stub.go") && file.IdenticalTo != filepath.Join("input", "a", "stub.go") {
+			t.Errorf("%s refers to %q, want input/a/stub.go", file.Path, file.IdenticalTo)
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	var lines []string
+	for i := 1; i <= 10; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+
+	s := newTestServer(t, map[string]string{
+		"big.txt": strings.Join(lines, "\n") + "\n",
+		"main.go": "package main\n\n// line 1 of main\n",
+	}, func(sc *sourcecollector.SourceCollector) {
+		sc.SizeLimit = &sourcecollector.SizeLimit{Max: 4, Unit: sourcecollector.SizeUnitLines, Policy: sourcecollector.OversizeHeadTail}
+	})
+
+	// Only lines 1, 2, 9 and 10 of big.txt are kept, the line numbers still match the file
+	text, isError := callTool(t, s, "search", `{"query":"line (1|9)","regex":true}`)
+	if isError {
+		t.Fatal(text)
+	}
+
+	want := "input/big.txt:1: line 1\ninput/big.txt:9: line 9\ninput/big.txt:10: line 10\ninput/main.go:3: // line 1 of main\n"
+	if text != want {
+		t.Errorf("search returned\n%s\nwant\n%s", text, want)
+	}
+
+	if text, _ := callTool(t, s, "search", `{"query":"line","max_results":2}`); !strings.HasSuffix(text, "... stopped after 2 matches\n") {
+		t.Errorf("search did not stop after 2 matches:\n%s", text)
+	}
+
+	if text, _ := callTool(t, s, "search", `{"query":"omitted"}`); text != "No matches found" {
+		t.Errorf("the elision marker is searched:\n%s", text)
+	}
+
+	if text, isError := callTool(t, s, "search", `{"query":"(","regex":true}`); !isError || !strings.Contains(text, ErrInvalidArgs.Error()) {
+		t.Errorf("invalid regex returned %q", text)
+	}
+}
+
+func TestUnknownTool(t *testing.T) {
+	s := newTestServer(t, nil)
+
+	if text, isError := callTool(t, s, "delete_files", `{}`); !isError || !strings.Contains(text, ErrUnknownTool.Error()) {
+		t.Errorf("unknown tool returned %q", text)
+	}
+}
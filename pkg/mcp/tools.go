@@ -0,0 +1,366 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	sourcecollector "github.com/hitesh22rana/sourcecollector/pkg"
+	"github.com/hitesh22rana/sourcecollector/pkg/validators"
+)
+
+// defaultMaxResults is the number of search matches returned when the client does not ask for more
+const defaultMaxResults = 100
+
+// tool describes a tool to the client
+type tool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"inputSchema"`
+}
+
+// patternProperties are the include and exclude arguments shared by the tools which walk the directory
+var patternProperties = map[string]any{
+	"include": map[string]any{
+		"type":        "array",
+		"items":       map[string]any{"type": "string"},
+		"description": "Only the files matching one of these globs, relative to the root (e.g. **/*.go)",
+	},
+	"exclude": map[string]any{
+		"type":        "array",
+		"items":       map[string]any{"type": "string"},
+		"description": "Skip the paths matching one of these globs, relative to the root (e.g. docs/**)",
+	},
+}
+
+// withPatterns adds the include and exclude arguments to the properties
+func withPatterns(properties map[string]any) map[string]any {
+	for name, property := range patternProperties {
+		properties[name] = property
+	}
+
+	return properties
+}
+
+// toolDefinitions are the tools of the server
+var toolDefinitions = []tool{
+	{
+		Name:        "list_tree",
+		Description: "List the directory tree of the collected files and their paths. Ignored files (e.g. by .gitignore) are left out.",
+		InputSchema: map[string]any{
+			"type":       "object",
+			"properties": withPatterns(map[string]any{}),
+		},
+	},
+	{
+		Name:        "read_files",
+		Description: "Read the content of files by the paths returned by list_tree, with secrets redacted if configured.",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"paths": map[string]any{
+					"type":        "array",
+					"items":       map[string]any{"type": "string"},
+					"description": "Paths of the files as returned by list_tree",
+				},
+			},
+			"required": []string{"paths"},
+		},
+	},
+	{
+		Name:        "collect",
+		Description: "Collect the tree and the content of all the matching files into one document in the output format of the server. Files over the size limit are left out, a file which does not fit in the token budget is skipped and the later ones are still collected if they fit. The files left out are listed in a second text block.",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": withPatterns(map[string]any{
+				"max_tokens": map[string]any{
+					"type":        "integer",
+					"description": "Estimated token budget of the file contents, references to duplicates are free, 0 means unlimited",
+				},
+			}),
+		},
+	},
+	{
+		Name:        "search",
+		Description: "Search the collected files line by line and return the matching lines as path:line: text.",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": withPatterns(map[string]any{
+				"query": map[string]any{
+					"type":        "string",
+					"description": "Text to search for, or a regular expression if regex is set",
+				},
+				"regex": map[string]any{
+					"type":        "boolean",
+					"description": "Treat the query as a regular expression",
+				},
+				"max_results": map[string]any{
+					"type":        "integer",
+					"description": fmt.Sprintf("Maximum number of matching lines, default %d", defaultMaxResults),
+				},
+			}),
+			"required": []string{"query"},
+		},
+	},
+}
+
+// toolArgs are the arguments of all the tools
+type toolArgs struct {
+	Include    []string `json:"include"`
+	Exclude    []string `json:"exclude"`
+	Paths      []string `json:"paths"`
+	MaxTokens  int      `json:"max_tokens"`
+	Query      string   `json:"query"`
+	Regex      bool     `json:"regex"`
+	MaxResults int      `json:"max_results"`
+}
+
+// toolResult is the result of a tool call, errors of the tool are reported in the result so the model can see them
+type toolResult struct {
+	Content []textContent `json:"content"`
+	IsError bool          `json:"isError,omitempty"`
+}
+
+// textContent is a text block of a tool result
+type textContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// callTool runs the tool and returns its result
+func (s *Server) callTool(ctx context.Context, name string, arguments json.RawMessage) *toolResult {
+	var args toolArgs
+	if len(arguments) > 0 {
+		if err := json.Unmarshal(arguments, &args); err != nil {
+			return errorResult(fmt.Errorf("%w: %v", ErrInvalidArgs, err))
+		}
+	}
+
+	var (
+		text  string
+		notes string
+		err   error
+	)
+	switch name {
+	case "list_tree":
+		text, err = s.listTree(ctx, &args)
+	case "read_files":
+		text, err = s.readFiles(&args)
+	case "collect":
+		text, notes, err = s.collect(ctx, &args)
+	case "search":
+		text, err = s.search(ctx, &args)
+	default:
+		err = fmt.Errorf("%w: %s", ErrUnknownTool, name)
+	}
+
+	if err != nil {
+		return errorResult(err)
+	}
+
+	// The notes are kept apart, so the text stays a valid document in every output format
+	result := &toolResult{Content: []textContent{{Type: "text", Text: text}}}
+	if notes != "" {
+		result.Content = append(result.Content, textContent{Type: "text", Text: notes})
+	}
+
+	return result
+}
+
+// errorResult reports the error of a tool to the model
+func errorResult(err error) *toolResult {
+	return &toolResult{Content: []textContent{{Type: "text", Text: err.Error()}}, IsError: true}
+}
+
+// newCollector makes the collector of a call narrowed down by the include and exclude arguments
+func (s *Server) newCollector(args *toolArgs) (*sourcecollector.SourceCollector, error) {
+	sc, err := s.NewCollector()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(args.Include) > 0 || len(args.Exclude) > 0 {
+		sc.Validator, err = validators.NewPatternValidator(sc.Validator, sc.Input, args.Include, args.Exclude)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return sc, nil
+}
+
+// generateSourceTree generates the source tree of the call and its structure
+func (s *Server) generateSourceTree(ctx context.Context, args *toolArgs) (*sourcecollector.SourceCollector, *sourcecollector.SourceTree, string, error) {
+	sc, err := s.newCollector(args)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	sourceTree, err := sc.GenerateSourceTree(ctx)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	sourceTreeStructure, err := sc.GenerateSourceTreeStructure(ctx, sourceTree)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	return sc, sourceTree, sourceTreeStructure, nil
+}
+
+// listTree returns the tree structure followed by the paths of the files
+func (s *Server) listTree(ctx context.Context, args *toolArgs) (string, error) {
+	sc, sourceTree, sourceTreeStructure, err := s.generateSourceTree(ctx, args)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	sb.WriteString(sourceTreeStructure)
+	sb.WriteString("\nFiles:\n")
+	for _, path := range sc.SourceFilePaths(sourceTree) {
+		sb.WriteString(path + "\n")
+	}
+
+	return sb.String(), nil
+}
+
+// readFiles returns the requested files in the format of the output, a file which cannot be read is reported in its place
+func (s *Server) readFiles(args *toolArgs) (string, error) {
+	if len(args.Paths) == 0 {
+		return "", fmt.Errorf("%w: paths is required", ErrInvalidArgs)
+	}
+
+	sc, err := s.newCollector(args)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	for _, path := range args.Paths {
+		file, err := sc.ReadSourceFile(path)
+		if err != nil {
+			fmt.Fprintf(&sb, "Error: %v\n\n", err)
+			continue
+		}
+
+		if file.SizeStatus == sourcecollector.SizeStatusSkipped {
+			fmt.Fprintf(&sb, "Skipped: %s exceeds the size limit\n\n", file.Path)
+			continue
+		}
+
+		sb.WriteString(sc.FormatSourceFile(file))
+	}
+
+	return sb.String(), nil
+}
+
+// collect returns the collection written like the output of the server, with notes listing the files which were left out
+func (s *Server) collect(ctx context.Context, args *toolArgs) (string, string, error) {
+	sc, sourceTree, sourceTreeStructure, err := s.generateSourceTree(ctx, args)
+	if err != nil {
+		return "", "", err
+	}
+	sc.MaxTokens = args.MaxTokens
+
+	// The files which could not be read are listed in the notes, unless they abort the collection
+	var (
+		sb            strings.Builder
+		collectionErr *sourcecollector.CollectionError
+	)
+	if err := sc.WriteSourceCode(ctx, &sb, sourceTree, sourceTreeStructure); err != nil {
+		if !errors.As(err, &collectionErr) || sc.OnError == sourcecollector.ErrorPolicyFail {
+			return "", "", err
+		}
+	}
+
+	var notes strings.Builder
+	if omitted := sc.OmittedPaths(); len(omitted) > 0 {
+		fmt.Fprintf(&notes, "Omitted %d file(s) over the budget of %d tokens:\n%s\n", len(omitted), args.MaxTokens, strings.Join(omitted, "\n"))
+	}
+
+	if collectionErr != nil {
+		failed := make([]string, len(collectionErr.Errors))
+		for i, fileErr := range collectionErr.Errors {
+			failed[i] = fileErr.Error()
+		}
+		fmt.Fprintf(&notes, "Failed to read %d file(s):\n%s\n", len(failed), strings.Join(failed, "\n"))
+	}
+
+	return sb.String(), notes.String(), nil
+}
+
+// search returns the lines of the files matching the query
+func (s *Server) search(ctx context.Context, args *toolArgs) (string, error) {
+	if args.Query == "" {
+		return "", fmt.Errorf("%w: query is required", ErrInvalidArgs)
+	}
+
+	pattern := regexp.QuoteMeta(args.Query)
+	if args.Regex {
+		pattern = args.Query
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidArgs, err)
+	}
+
+	maxResults := args.MaxResults
+	if maxResults <= 0 {
+		maxResults = defaultMaxResults
+	}
+
+	sc, sourceTree, _, err := s.generateSourceTree(ctx, args)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	matches := 0
+	for _, path := range sc.SourceFilePaths(sourceTree) {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+
+		file, err := sc.ReadSourceFile(path)
+		if err != nil {
+			continue
+		}
+
+		lineNumber := 0
+		for _, line := range strings.Split(file.Content, "\n") {
+			lineNumber++
+
+			// The lines cut out by the size limit still count, so the line numbers match the file
+			if file.SizeStatus == sourcecollector.SizeStatusTruncated {
+				if elided := sourcecollector.ElidedLines(line); elided > 0 {
+					lineNumber += elided - 1
+					continue
+				}
+			}
+
+			if !re.MatchString(line) {
+				continue
+			}
+
+			if matches == maxResults {
+				fmt.Fprintf(&sb, "... stopped after %d matches\n", maxResults)
+				return sb.String(), nil
+			}
+
+			fmt.Fprintf(&sb, "%s:%d: %s\n", file.Path, lineNumber, line)
+			matches++
+		}
+	}
+
+	if matches == 0 {
+		return "No matches found", nil
+	}
+
+	return sb.String(), nil
+}
@@ -0,0 +1,25 @@
+package pkg
+
+import (
+	"github.com/hitesh22rana/sourcecollector/pkg/outline"
+)
+
+// outlineSourceFile replaces the content of a file with its outline when outlines are enabled and there is a symbol extractor for the file.
+// A file which cannot be parsed is kept as is.
+func (sc *SourceCollector) outlineSourceFile(path string, data []byte) []byte {
+	if !sc.Outline {
+		return data
+	}
+
+	extractor := outline.ForPath(path)
+	if extractor == nil {
+		return data
+	}
+
+	out, err := extractor.Extract(data)
+	if err != nil {
+		return data
+	}
+
+	return out
+}
@@ -0,0 +1,7 @@
+package outline
+
+import "errors"
+
+var (
+	ErrInvalidSource = errors.New("failed to parse source")
+)
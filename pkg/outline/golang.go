@@ -0,0 +1,63 @@
+package outline
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+)
+
+// GoExtractor extracts the outline of Go files with go/parser: the package clause, the imports, the type declarations and the function and method signatures, with their doc comments.
+// Function bodies, constants and variables are left out.
+type GoExtractor struct{}
+
+// Extract returns the outline of the Go source
+func (GoExtractor) Extract(src []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSource, err)
+	}
+
+	var out bytes.Buffer
+	config := printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 8}
+
+	if file.Doc != nil {
+		for _, comment := range file.Doc.List {
+			out.WriteString(comment.Text + "\n")
+		}
+	}
+	out.WriteString("package " + file.Name.Name + "\n")
+
+	for _, decl := range file.Decls {
+		var node ast.Node
+		switch decl := decl.(type) {
+		case *ast.GenDecl:
+			if decl.Tok != token.IMPORT && decl.Tok != token.TYPE {
+				continue
+			}
+			node = decl
+
+		case *ast.FuncDecl:
+			// Only the signature is kept, so the comments inside the body fall outside of the printed node
+			signature := *decl
+			signature.Body = nil
+			node = &signature
+
+		default:
+			continue
+		}
+
+		out.WriteByte('\n')
+
+		// The printer only writes the comments within the node and its doc comment
+		if err := config.Fprint(&out, fset, &printer.CommentedNode{Node: node, Comments: file.Comments}); err != nil {
+			return nil, err
+		}
+		out.WriteByte('\n')
+	}
+
+	return out.Bytes(), nil
+}
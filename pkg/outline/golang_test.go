@@ -0,0 +1,70 @@
+package outline
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGoExtractor(t *testing.T) {
+	src := `// Package shop sells things.
+package shop
+
+import "fmt"
+
+const tax = 0.2
+
+var registry = map[string]*Item{}
+
+// Item is something for sale
+type Item struct {
+	// Name of the item
+	Name  string
+	Price float64
+}
+
+// Total returns the price with tax
+func (i *Item) Total() float64 {
+	// the tax is added here
+	return i.Price * (1 + tax)
+}
+
+func Print(items ...*Item) {
+	for _, item := range items {
+		fmt.Println(item.Name)
+	}
+}
+`
+
+	want := `// Package shop sells things.
+package shop
+
+import "fmt"
+
+// Item is something for sale
+type Item struct {
+	// Name of the item
+	Name  string
+	Price float64
+}
+
+// Total returns the price with tax
+func (i *Item) Total() float64
+
+func Print(items ...*Item)
+`
+
+	got, err := GoExtractor{}.Extract([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(got) != want {
+		t.Errorf("outline:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestGoExtractorInvalidSource(t *testing.T) {
+	if _, err := (GoExtractor{}).Extract([]byte("package broken {\n")); !errors.Is(err, ErrInvalidSource) {
+		t.Errorf("error %v, want %v", err, ErrInvalidSource)
+	}
+}
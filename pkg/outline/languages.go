@@ -0,0 +1,69 @@
+package outline
+
+import "regexp"
+
+// PythonExtractor keeps the imports and the def and class declarations with their decorators and docstrings
+var PythonExtractor = &LineExtractor{
+	Imports: []*regexp.Regexp{
+		regexp.MustCompile(`^(import|from)\s+\S+`),
+	},
+	Declarations: []*regexp.Regexp{
+		regexp.MustCompile(`^\s*(async\s+)?def\s+\w+`),
+		regexp.MustCompile(`^\s*class\s+\w+`),
+	},
+	LineComment: "#",
+	Decorators:  true,
+	Docstrings:  true,
+}
+
+// TypeScriptExtractor keeps the imports and re-exports, the function, class, interface, type and enum declarations, the exports and the class methods of TypeScript and JavaScript files
+var TypeScriptExtractor = &LineExtractor{
+	Imports: []*regexp.Regexp{
+		regexp.MustCompile(`^\s*import\b\s*[\w$*{"'\x60]`),
+		regexp.MustCompile(`^\s*export\s+(type\s+)?(\*|\{).*\bfrom\s*["'\x60]`),
+		regexp.MustCompile(`^\s*export\s+(type\s+)?\{[^}]*$`),
+	},
+	Declarations: []*regexp.Regexp{
+		regexp.MustCompile(`^\s*(export\s+)?(default\s+)?(declare\s+)?(async\s+)?function\b`),
+		regexp.MustCompile(`^\s*(export\s+)?(default\s+)?(declare\s+)?(abstract\s+)?class\b`),
+		regexp.MustCompile(`^\s*(declare\s+)?(interface|type|enum)\s+\w`),
+		regexp.MustCompile(`^\s*export\b`),
+		regexp.MustCompile(`^\s+((public|private|protected|static|async|readonly|abstract|override|get|set)\s+)*[A-Za-z_$#][\w$]*\s*(<[^>]*>)?\s*\(.*\)\s*(:\s*[^={]+)?\{\s*$`),
+	},
+	Statements:    regexp.MustCompile(`^\s*(return|if|else|for|while|switch|case|catch|do|try|new|throw|await|yield)\b`),
+	LineComment:   "//",
+	BlockComments: true,
+	Decorators:    true,
+	Braces:        true,
+}
+
+// JavaExtractor keeps the package, the imports and the class, interface, enum, record and method declarations with their annotations and Javadoc
+var JavaExtractor = &LineExtractor{
+	Imports: []*regexp.Regexp{
+		regexp.MustCompile(`^\s*(package|import)\s+[\w.*\s]+;`),
+	},
+	Declarations: []*regexp.Regexp{
+		regexp.MustCompile(`^\s*((public|protected|private|abstract|final|static|sealed|non-sealed|strictfp)\s+)*(class|interface|enum|record|@interface)\s+\w+`),
+		regexp.MustCompile(`^\s*((public|protected|private|abstract|final|static|synchronized|native|default|strictfp)\s+)*(<[^>]+>\s+)?[\w.<>\[\]?, ]*[\w>\]]\s+\w+\s*\(`),
+	},
+	Statements:    regexp.MustCompile(`^\s*(return|new|else|throw|case|if|for|while|switch|catch|try|do|yield|assert)\b`),
+	LineComment:   "//",
+	BlockComments: true,
+	Decorators:    true,
+	Braces:        true,
+}
+
+// KotlinExtractor keeps the package, the imports and the class, interface, object and fun declarations with their annotations and KDoc
+var KotlinExtractor = &LineExtractor{
+	Imports: []*regexp.Regexp{
+		regexp.MustCompile(`^\s*(package|import)\s+[\w.*]+`),
+	},
+	Declarations: []*regexp.Regexp{
+		regexp.MustCompile(`^\s*((public|private|protected|internal|abstract|open|final|sealed|data|enum|annotation|inner|value|inline|companion|expect|actual|fun)\s+)*(class|interface|object)\b`),
+		regexp.MustCompile(`^\s*((public|private|protected|internal|abstract|open|final|override|suspend|inline|operator|infix|tailrec|external|expect|actual)\s+)*fun\b`),
+	},
+	LineComment:   "//",
+	BlockComments: true,
+	Decorators:    true,
+	Braces:        true,
+}
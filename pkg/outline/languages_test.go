@@ -0,0 +1,215 @@
+package outline
+
+import "testing"
+
+func TestLineExtractors(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		src  string
+		want string
+	}{
+		{
+			name: "python",
+			path: "shop/cart.py",
+			src: `import os
+from typing import (
+    Dict,
+    List,  # the items
+)
+
+TAX = 0.2
+
+
+@dataclass
+class Item(Base):
+    """Something for sale."""
+
+    def total(self,
+              tax: float = TAX) -> float:
+        # the tax is added here
+        return self.price * (1 + tax)
+
+    def label(self, sep="(") -> str:
+        return sep.join([self.name, ")"])
+
+    def describe(self,
+                 prefix="# ("):  # not a comment (
+        return prefix + self.name
+
+
+async def checkout(items: List[Item]) -> None:
+    """Pays for the items.
+
+    Raises if the payment fails.
+    """
+    for item in items:
+        print(item)
+`,
+			want: `import os
+from typing import (
+    Dict,
+    List,  # the items
+)
+
+@dataclass
+class Item(Base):
+    """Something for sale."""
+    def total(self,
+              tax: float = TAX) -> float:
+    def label(self, sep="(") -> str:
+    def describe(self,
+                 prefix="# ("):  # not a comment (
+
+async def checkout(items: List[Item]) -> None:
+    """Pays for the items.
+
+    Raises if the payment fails.
+    """
+`,
+		},
+		{
+			name: "typescript",
+			path: "web/cart.ts",
+			src: `import { api } from "./api";
+import {
+  Money,
+  Currency,
+} from "./money";
+export * from "./item";
+
+const TAX = 0.2;
+
+/** Something for sale */
+export class Item {
+  constructor(private price: number) {}
+
+  total(tax: number = TAX): number {
+    if (tax > 1) {
+      throw new Error("tax");
+    }
+    return this.price * (1 + tax);
+  }
+}
+
+export interface Cart { items: Item[] }
+
+export async function checkout(items: Item[]): Promise<void> {
+  await api.pay(items);
+}
+`,
+			want: `import { api } from "./api";
+import {
+  Money,
+  Currency,
+} from "./money";
+export * from "./item";
+
+/** Something for sale */
+export class Item
+  total(tax: number = TAX): number
+
+export interface Cart { items: Item[] }
+
+export async function checkout(items: Item[]): Promise<void>
+`,
+		},
+		{
+			name: "java",
+			path: "src/Cart.java",
+			src: `package shop;
+
+import java.util.List;
+
+/**
+ * Something for sale.
+ */
+public class Item {
+    private double price;
+
+    @Override
+    public String toString() {
+        return String.valueOf(price);
+    }
+
+    public static <T extends Item> double total(List<T> items, double tax) {
+        double sum = compute(items);
+        if (sum > 0) {
+            return sum * (1 + tax);
+        }
+        return 0;
+    }
+}
+`,
+			want: `package shop;
+import java.util.List;
+
+/**
+ * Something for sale.
+ */
+public class Item
+    @Override
+    public String toString()
+    public static <T extends Item> double total(List<T> items, double tax)
+`,
+		},
+		{
+			name: "kotlin",
+			path: "src/Cart.kt",
+			src: `package shop
+
+import kotlin.math.max
+
+/** Something for sale */
+data class Item(val price: Double) {
+    override fun toString(): String {
+        return price.toString()
+    }
+
+    fun total(tax: Double = 0.2) = price * (1 + tax)
+}
+
+object Checkout {
+    suspend fun pay(items: List<Item>) {
+        items.forEach { println(it) }
+    }
+}
+`,
+			want: `package shop
+import kotlin.math.max
+
+/** Something for sale */
+data class Item(val price: Double)
+    override fun toString(): String
+    fun total(tax: Double = 0.2) = price * (1 + tax)
+
+object Checkout
+    suspend fun pay(items: List<Item>)
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			extractor := ForPath(tt.path)
+			if extractor == nil {
+				t.Fatalf("no extractor for %s", tt.path)
+			}
+
+			got, err := extractor.Extract([]byte(tt.src))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(got) != tt.want {
+				t.Errorf("outline:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestForPathUnknownExtension(t *testing.T) {
+	if extractor := ForPath("notes.txt"); extractor != nil {
+		t.Errorf("extractor %T for a text file", extractor)
+	}
+}
@@ -0,0 +1,227 @@
+package outline
+
+import (
+	"regexp"
+	"strings"
+)
+
+// LineExtractor extracts the outline of a source file line by line with regular expressions, without parsing it.
+// It keeps the matching declarations with the comments and decorators right above them, and cuts off the bodies opened at the end of their line.
+type LineExtractor struct {
+	// Imports match the lines kept as they are, e.g. package and import statements
+	Imports []*regexp.Regexp
+
+	// Declarations match the first line of the declarations, a declaration goes on until its parentheses are balanced
+	Declarations []*regexp.Regexp
+
+	// Statements match the lines which are never declarations, e.g. those starting with return or else
+	Statements *regexp.Regexp
+
+	// LineComment starts a comment which runs to the end of the line
+	LineComment string
+
+	// BlockComments tells if the language has /* */ comments
+	BlockComments bool
+
+	// Decorators tells if the lines starting with @ belong to the declaration below them
+	Decorators bool
+
+	// Docstrings tells if the triple quoted string right below a declaration is its documentation
+	Docstrings bool
+
+	// Braces tells if the bodies are in braces, the brace opening a body at the end of a declaration is then cut off
+	Braces bool
+}
+
+// decoratorLine matches an annotation or decorator on a line of its own
+var decoratorLine = regexp.MustCompile(`^@[\w.]+(\(.*\))?$`)
+
+// Extract returns the outline of the source, it never fails
+func (e *LineExtractor) Extract(src []byte) ([]byte, error) {
+	lines := strings.Split(strings.ReplaceAll(string(src), "\r\n", "\n"), "\n")
+
+	var out outlineWriter
+
+	// pending are the comments and decorators since the last blank line or statement
+	var pending []string
+
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], " \t")
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			pending = nil
+
+		case e.BlockComments && strings.HasPrefix(trimmed, "/*"):
+			// The comment runs until the line which closes it
+			end := i
+			for end < len(lines)-1 && !strings.Contains(lines[end], "*/") {
+				end++
+			}
+			pending = append(pending, trimLines(lines[i:end+1])...)
+			i = end
+
+		case e.LineComment != "" && strings.HasPrefix(trimmed, e.LineComment):
+			pending = append(pending, line)
+
+		case e.Decorators && decoratorLine.MatchString(trimmed):
+			pending = append(pending, line)
+
+		case matchAny(e.Imports, line):
+			// An import can list its names over several lines
+			end := i
+			for depth := e.bracketDepth(line, "({"); depth > 0 && end < len(lines)-1; {
+				end++
+				depth += e.bracketDepth(lines[end], "({")
+			}
+			out.group(append(pending, trimLines(lines[i:end+1])...), true)
+			i = end
+			pending = nil
+
+		case matchAny(e.Declarations, line) && (e.Statements == nil || !e.Statements.MatchString(line)):
+			// A signature can be split over several lines
+			end := i
+			for depth := e.bracketDepth(line, "("); depth > 0 && end < len(lines)-1; {
+				end++
+				depth += e.bracketDepth(lines[end], "(")
+			}
+			declaration := trimLines(lines[i : end+1])
+			i = end
+
+			if e.Braces {
+				last := len(declaration) - 1
+				declaration[last] = strings.TrimRight(strings.TrimSuffix(declaration[last], "{"), " \t")
+			}
+
+			if e.Docstrings {
+				if docstring := docstringAt(lines, i+1); len(docstring) > 0 {
+					declaration = append(declaration, docstring...)
+					i += len(docstring)
+				}
+			}
+
+			out.group(append(pending, declaration...), false)
+			pending = nil
+
+		default:
+			pending = nil
+		}
+	}
+
+	return []byte(out.String()), nil
+}
+
+// outlineWriter writes the groups of lines of an outline, a declaration with its comments is a group
+type outlineWriter struct {
+	strings.Builder
+
+	// lastImport tells if the last group was an import, so the imports stay together
+	lastImport bool
+}
+
+// group writes a group of lines, top level groups are separated by a blank line
+func (w *outlineWriter) group(lines []string, isImport bool) {
+	topLevel := lines[0] == strings.TrimLeft(lines[0], " \t")
+	if w.Len() > 0 && topLevel && !(isImport && w.lastImport) {
+		w.WriteByte('\n')
+	}
+	w.lastImport = isImport
+
+	for _, line := range lines {
+		w.WriteString(line + "\n")
+	}
+}
+
+// docstringAt returns the lines of the triple quoted string starting at the line i, nil if there is none
+func docstringAt(lines []string, i int) []string {
+	if i >= len(lines) {
+		return nil
+	}
+
+	trimmed := strings.TrimLeft(strings.TrimSpace(lines[i]), "rRuUbB")
+	var quote string
+	switch {
+	case strings.HasPrefix(trimmed, `"""`):
+		quote = `"""`
+	case strings.HasPrefix(trimmed, `'''`):
+		quote = `'''`
+	default:
+		return nil
+	}
+
+	// The docstring can close on its first line
+	end := i
+	if !strings.Contains(trimmed[len(quote):], quote) {
+		end++
+		for end < len(lines)-1 && !strings.Contains(lines[end], quote) {
+			end++
+		}
+	}
+
+	return trimLines(lines[i : end+1])
+}
+
+// brackets are the closing brackets of the opening ones
+var brackets = map[byte]byte{'(': ')', '{': '}', '[': ']'}
+
+// bracketDepth returns how many more of the opening brackets the line opens than it closes.
+// The brackets in quoted strings and comments are not counted, e.g. def f(x="("):
+func (e *LineExtractor) bracketDepth(line string, opening string) int {
+	closing := make([]byte, len(opening))
+	for i := range opening {
+		closing[i] = brackets[opening[i]]
+	}
+
+	depth := 0
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == '"' || c == '\'' || c == '`':
+			// Skip to the closing quote, past the escaped characters
+			for i++; i < len(line) && line[i] != c; i++ {
+				if line[i] == '\\' {
+					i++
+				}
+			}
+
+		case e.LineComment != "" && strings.HasPrefix(line[i:], e.LineComment):
+			return depth
+
+		case e.BlockComments && strings.HasPrefix(line[i:], "/*"):
+			end := strings.Index(line[i+2:], "*/")
+			if end < 0 {
+				return depth
+			}
+			i += end + 3
+
+		case strings.IndexByte(opening, c) >= 0:
+			depth++
+
+		case strings.IndexByte(string(closing), c) >= 0:
+			depth--
+		}
+	}
+
+	return depth
+}
+
+// matchAny reports whether any of the patterns matches the line
+func matchAny(patterns []*regexp.Regexp, line string) bool {
+	for _, pattern := range patterns {
+		if pattern.MatchString(line) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// trimLines returns a copy of the lines without their trailing spaces
+func trimLines(lines []string) []string {
+	trimmed := make([]string, len(lines))
+	for i, line := range lines {
+		trimmed[i] = strings.TrimRight(line, " \t")
+	}
+
+	return trimmed
+}
@@ -0,0 +1,53 @@
+package outline
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// SymbolExtractor extracts the outline of a source file: its declarations and signatures, without the bodies
+type SymbolExtractor interface {
+	// Extract returns the outline of the source
+	Extract(src []byte) ([]byte, error)
+}
+
+var (
+	extractorsMu sync.RWMutex
+
+	// extractors by the file extensions they handle, with the leading dot
+	extractors = map[string]SymbolExtractor{
+		".go":   GoExtractor{},
+		".py":   PythonExtractor,
+		".pyi":  PythonExtractor,
+		".js":   TypeScriptExtractor,
+		".jsx":  TypeScriptExtractor,
+		".mjs":  TypeScriptExtractor,
+		".cjs":  TypeScriptExtractor,
+		".ts":   TypeScriptExtractor,
+		".tsx":  TypeScriptExtractor,
+		".mts":  TypeScriptExtractor,
+		".cts":  TypeScriptExtractor,
+		".java": JavaExtractor,
+		".kt":   KotlinExtractor,
+		".kts":  KotlinExtractor,
+	}
+)
+
+// Register sets the extractor of the file extensions, replacing the extractor they had
+func Register(extractor SymbolExtractor, extensions ...string) {
+	extractorsMu.Lock()
+	defer extractorsMu.Unlock()
+
+	for _, extension := range extensions {
+		extractors[strings.ToLower(extension)] = extractor
+	}
+}
+
+// ForPath returns the extractor of the extension of the path, nil if there is none
+func ForPath(path string) SymbolExtractor {
+	extractorsMu.RLock()
+	defer extractorsMu.RUnlock()
+
+	return extractors[strings.ToLower(filepath.Ext(path))]
+}
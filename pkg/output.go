@@ -0,0 +1,132 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// WriteMode decides what happens to an existing output file
+type WriteMode string
+
+const (
+	// WriteModeOverwrite replaces the existing output file
+	WriteModeOverwrite WriteMode = "overwrite"
+
+	// WriteModeNoClobber fails if the output file already exists
+	WriteModeNoClobber WriteMode = "no-clobber"
+
+	// WriteModeAppend adds the collection to the end of the existing output file
+	WriteModeAppend WriteMode = "append"
+)
+
+// outputFile is a temporary file in the directory of the output, which only replaces the output once committed
+type outputFile struct {
+	*os.File
+
+	// path of the output file
+	path string
+
+	// mode in which the output file is written
+	mode WriteMode
+
+	// perm of the output file
+	perm fs.FileMode
+}
+
+// createOutputFile creates the temporary file for the output of the collection.
+// A json collection cannot be appended to, the output would hold two json documents.
+func (sc *SourceCollector) createOutputFile() (*outputFile, error) {
+	if sc.WriteMode == WriteModeAppend && sc.Format == FormatJSON {
+		return nil, fmt.Errorf("%w: a %s collection cannot be appended to an output", ErrInvalidWriteMode, sc.Format)
+	}
+
+	return createOutputFile(sc.Output, sc.WriteMode)
+}
+
+// createOutputFile creates the temporary file for the output, in append mode it starts with the content of the existing output
+func createOutputFile(path string, mode WriteMode) (*outputFile, error) {
+	perm := fs.FileMode(0644)
+
+	existing, err := os.Stat(path)
+	switch {
+	case err == nil && mode == WriteModeNoClobber:
+		return nil, fmt.Errorf("%w: %s", ErrOutputExists, path)
+	case err == nil:
+		perm = existing.Mode().Perm()
+	case !errors.Is(err, fs.ErrNotExist):
+		return nil, fmt.Errorf("%w: %v", ErrOpenOutputFile, err)
+	}
+
+	// The temporary file starts with a dot, so it is never collected itself
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOpenOutputFile, err)
+	}
+
+	out := &outputFile{
+		File: file,
+		path: path,
+		mode: mode,
+		perm: perm,
+	}
+
+	if mode == WriteModeAppend && existing != nil {
+		if err := out.copyFrom(path); err != nil {
+			out.discard()
+			return nil, fmt.Errorf("%w: %v", ErrOpenOutputFile, err)
+		}
+	}
+
+	return out, nil
+}
+
+// copyFrom copies the content of the file at path into the output file
+func (f *outputFile) copyFrom(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	_, err = io.Copy(f.File, src)
+	return err
+}
+
+// commit atomically moves the temporary file to the output path
+func (f *outputFile) commit() error {
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("%w: %v", ErrWriteOutputFile, err)
+	}
+
+	if err := os.Chmod(f.Name(), f.perm); err != nil {
+		return fmt.Errorf("%w: %v", ErrWriteOutputFile, err)
+	}
+
+	// A hard link fails if the output was created in the meantime, so it cannot be clobbered
+	if f.mode == WriteModeNoClobber {
+		if err := os.Link(f.Name(), f.path); err != nil {
+			if errors.Is(err, fs.ErrExist) {
+				return fmt.Errorf("%w: %s", ErrOutputExists, f.path)
+			}
+			return fmt.Errorf("%w: %v", ErrWriteOutputFile, err)
+		}
+
+		return os.Remove(f.Name())
+	}
+
+	if err := os.Rename(f.Name(), f.path); err != nil {
+		return fmt.Errorf("%w: %v", ErrWriteOutputFile, err)
+	}
+
+	return nil
+}
+
+// discard removes the temporary file, it is a no-op after commit
+func (f *outputFile) discard() {
+	f.Close()
+	os.Remove(f.Name())
+}
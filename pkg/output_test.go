@@ -0,0 +1,202 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hitesh22rana/sourcecollector/pkg/internal/testutil"
+)
+
+// outputFiles are the files collected by the tests of the output file
+var outputFiles = map[string]string{
+	"main.go":      "package main\n",
+	"util/util.go": "package util\n",
+}
+
+// newOutputCollector writes the files into an input directory and creates a collector of it, writing to output.txt next to the input
+func newOutputCollector(t *testing.T, files map[string]string) *SourceCollector {
+	t.Helper()
+
+	input := testutil.WriteFiles(t, files)
+	sc, err := NewSourceCollector(input, filepath.Join(filepath.Dir(input), "output.txt"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc.Validator = testutil.AcceptAll{}
+
+	return sc
+}
+
+// saveSourceCode generates the source tree of the collector and saves it with the context, before is called between the two
+func saveSourceCode(t *testing.T, ctx context.Context, sc *SourceCollector, before func()) error {
+	t.Helper()
+
+	sourceTree, err := sc.GenerateSourceTree(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sourceTreeStructure, err := sc.GenerateSourceTreeStructure(context.Background(), sourceTree)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if before != nil {
+		before()
+	}
+
+	return sc.SaveSourceCode(ctx, sourceTree, sourceTreeStructure)
+}
+
+// assertOutput checks the content of the output and that no temporary file is left next to it
+func assertOutput(t *testing.T, sc *SourceCollector, want string) {
+	t.Helper()
+
+	got, err := os.ReadFile(sc.Output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("output %q, want %q", got, want)
+	}
+
+	temporary, err := filepath.Glob(filepath.Join(filepath.Dir(sc.Output), ".output.txt.*.tmp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(temporary) > 0 {
+		t.Errorf("temporary files left behind: %v", temporary)
+	}
+}
+
+func TestSaveSourceCodeKeepsPreviousOutput(t *testing.T) {
+	const previous = "previous collection\n"
+
+	tests := []struct {
+		name string
+		run  func(t *testing.T, sc *SourceCollector) error
+	}{
+		{
+			name: "cancelled",
+			run: func(t *testing.T, sc *SourceCollector) error {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+
+				return saveSourceCode(t, ctx, sc, nil)
+			},
+		},
+		{
+			name: "failed",
+			run: func(t *testing.T, sc *SourceCollector) error {
+				sc.OnError = ErrorPolicyFail
+
+				return saveSourceCode(t, context.Background(), sc, func() {
+					if err := os.Remove(filepath.Join(sc.Input, "main.go")); err != nil {
+						t.Fatal(err)
+					}
+				})
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sc := newOutputCollector(t, outputFiles)
+			if err := os.WriteFile(sc.Output, []byte(previous), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := test.run(t, sc); err == nil {
+				t.Fatal("the run succeeded")
+			}
+
+			assertOutput(t, sc, previous)
+		})
+	}
+}
+
+func TestSaveSourceCodeNoClobber(t *testing.T) {
+	const existing = "existing file\n"
+
+	sc := newOutputCollector(t, outputFiles)
+	sc.WriteMode = WriteModeNoClobber
+	if err := os.WriteFile(sc.Output, []byte(existing), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := saveSourceCode(t, context.Background(), sc, nil); !errors.Is(err, ErrOutputExists) {
+		t.Fatalf("error %v, want %v", err, ErrOutputExists)
+	}
+	assertOutput(t, sc, existing)
+
+	// An output created while the collection runs is not clobbered either
+	if err := os.Remove(sc.Output); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := sc.createOutputFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(sc.Output, []byte(existing), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.commit(); !errors.Is(err, ErrOutputExists) {
+		t.Fatalf("error %v, want %v", err, ErrOutputExists)
+	}
+	file.discard()
+	assertOutput(t, sc, existing)
+}
+
+func TestSaveSourceCodeAppend(t *testing.T) {
+	const earlier = "earlier collection\n"
+
+	sc := newOutputCollector(t, outputFiles)
+	sc.WriteMode = WriteModeAppend
+	if err := os.WriteFile(sc.Output, []byte(earlier), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := saveSourceCode(t, context.Background(), sc, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := os.ReadFile(sc.Output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	collection, ok := strings.CutPrefix(string(got), earlier)
+	if !ok {
+		t.Fatalf("output %q does not start with the earlier content", got)
+	}
+
+	bundle, err := ParseBundle([]byte(collection))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bundle) != len(outputFiles) {
+		t.Errorf("appended %d files, want %d", len(bundle), len(outputFiles))
+	}
+}
+
+func TestSaveSourceCodeAppendJSON(t *testing.T) {
+	const earlier = "{\"files\":[]}\n"
+
+	sc := newOutputCollector(t, outputFiles)
+	sc.WriteMode = WriteModeAppend
+	sc.Format = FormatJSON
+	if err := os.WriteFile(sc.Output, []byte(earlier), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := saveSourceCode(t, context.Background(), sc, nil); !errors.Is(err, ErrInvalidWriteMode) {
+		t.Fatalf("error %v, want %v", err, ErrInvalidWriteMode)
+	}
+	assertOutput(t, sc, earlier)
+}
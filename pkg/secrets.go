@@ -0,0 +1,62 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/hitesh22rana/sourcecollector/pkg/secrets"
+)
+
+// ScanSecrets runs the secret detectors of the redactor over the source files without writing anything to the output.
+// The files which cannot be read follow sc.OnError like the collection: they are skipped and returned as a *CollectionError along with the findings,
+// or the scan stops at the first one and only the *CollectionError is returned.
+func (sc *SourceCollector) ScanSecrets(ctx context.Context, sourceTree *SourceTree) ([]secrets.Finding, error) {
+	// Check if the source tree is nil or there is nothing to scan with
+	if sourceTree == nil || sc.Redactor == nil {
+		return nil, ErrScanSecrets
+	}
+
+	// Abort the scan on cancellation or on the first error which should not be skipped
+	parentCtx := ctx
+	ctx, abort := context.WithCancel(ctx)
+	defer abort()
+
+	var (
+		mu         sync.Mutex
+		findings   = []secrets.Finding{}
+		fileErrors []*FileError
+	)
+
+	// Read and scan the source code files concurrently
+	sc.readSourceFiles(ctx, sourceTree, func(node SourceNode, relPath string, content []byte, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if err != nil {
+			fileErrors = append(fileErrors, &FileError{Path: relPath, Err: err})
+			if sc.OnError == ErrorPolicyFail {
+				abort()
+			}
+			return
+		}
+
+		findings = append(findings, sc.Redactor.Scan(relPath, content)...)
+	})
+
+	if err := parentCtx.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrScanSecrets, err)
+	}
+
+	if len(fileErrors) > 0 && sc.OnError == ErrorPolicyFail {
+		return nil, newCollectionError(fileErrors)
+	}
+
+	secrets.SortFindings(findings)
+
+	if len(fileErrors) > 0 {
+		return findings, newCollectionError(fileErrors)
+	}
+
+	return findings, nil
+}
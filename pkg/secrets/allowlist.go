@@ -0,0 +1,35 @@
+package secrets
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// LoadAllowlist reads the accepted fingerprints from a file, one per line, blank lines and lines starting with # are skipped
+func LoadAllowlist(path string) (map[string]struct{}, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrReadAllowlist, err)
+	}
+	defer file.Close()
+
+	allowlist := make(map[string]struct{})
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// Allow a trailing comment after the fingerprint
+		allowlist[strings.Fields(line)[0]] = struct{}{}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrReadAllowlist, err)
+	}
+
+	return allowlist, nil
+}
@@ -0,0 +1,36 @@
+package secrets
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadAllowlist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "allowlist")
+	content := "# false positives\n\n63ddfff3720c7202\n  0123456789abcdef   test fixture\n#fedcba9876543210\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	allowlist, err := LoadAllowlist(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(allowlist) != 2 {
+		t.Errorf("allowlist %v, want 2 fingerprints", allowlist)
+	}
+	for _, fingerprint := range []string{"63ddfff3720c7202", "0123456789abcdef"} {
+		if _, ok := allowlist[fingerprint]; !ok {
+			t.Errorf("fingerprint %s is missing", fingerprint)
+		}
+	}
+}
+
+func TestLoadAllowlistMissingFile(t *testing.T) {
+	if _, err := LoadAllowlist(filepath.Join(t.TempDir(), "missing")); !errors.Is(err, ErrReadAllowlist) {
+		t.Errorf("error %v, want %v", err, ErrReadAllowlist)
+	}
+}
@@ -0,0 +1,8 @@
+package secrets
+
+import "errors"
+
+var (
+	ErrInvalidPattern = errors.New("invalid secret pattern")
+	ErrReadAllowlist  = errors.New("failed to read secrets allowlist")
+)
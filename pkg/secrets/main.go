@@ -0,0 +1,168 @@
+package secrets
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+const (
+	// secretGroup is the name of the capture group holding the secret value, when only a part of the match is sensitive
+	secretGroup = "secret"
+
+	// minGenericSecretEntropy is the minimum shannon entropy (bits per char) for a generic assignment to be treated as a secret
+	minGenericSecretEntropy = 3.5
+
+	// minGenericSecretLength is the minimum length of the value of a generic assignment to be treated as a secret
+	minGenericSecretLength = 12
+)
+
+var (
+	builtinDetectors = []*Detector{
+		{
+			Kind:    "aws-access-key",
+			Pattern: regexp.MustCompile(`\b(?:AKIA|ASIA|AGPA|AIDA|AROA|ANPA|ANVA|AIPA)[0-9A-Z]{16}\b`),
+		},
+		{
+			Kind:    "aws-secret-key",
+			Pattern: regexp.MustCompile(`(?i)aws_?secret_?(?:access_?)?key["']?\s*[:=]\s*["']?(?P<secret>[A-Za-z0-9/+=]{40})\b`),
+		},
+		{
+			Kind:    "github-token",
+			Pattern: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,255}|github_pat_[A-Za-z0-9_]{22,255})\b`),
+		},
+		{
+			Kind:    "private-key",
+			Pattern: regexp.MustCompile(`-----BEGIN[A-Z ]*PRIVATE KEY( BLOCK)?-----[\s\S]*?-----END[A-Z ]*PRIVATE KEY( BLOCK)?-----`),
+		},
+		{
+			Kind:    "jwt",
+			Pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`),
+		},
+		{
+			Kind:    "generic-secret",
+			Pattern: regexp.MustCompile(`(?i)\b(?:password|passwd|pwd|secret|client_?secret|api_?key|access_?token|auth_?token)["']?\s*[:=]\s*["']?(?P<secret>[^\s"'` + "`" + `,;]{8,})`),
+			Accept:  isGenericSecret,
+		},
+	}
+
+	// codeReference matches a value which is an identifier, a selector chain or a call rather than a literal, e.g. cfg.Password or os.Getenv(
+	codeReference = regexp.MustCompile(`^[A-Za-z_][\w.]*(\(.*)?$`)
+
+	// placeholderMarkers are the parts of values which only appear in examples rather than real secrets, e.g. changeme123 or sk-xxxx
+	placeholderMarkers = []string{"changeme", "change_me", "change-me", "placeholder", "example", "dummy", "redacted", "xxxx", "****", "notasecret"}
+
+	// placeholderWords are the values which are placeholders only as a whole, e.g. password1 but not MySecretProd9x
+	placeholderWords = []string{"secret", "password", "passwd", "token", "apikey", "api_key", "todo", "none", "null"}
+
+	// placeholderPrefixes start the values which name what goes in their place, e.g. your-api-key
+	placeholderPrefixes = []string{"your-", "your_", "insert-", "insert_"}
+)
+
+// Detector is a named pattern that identifies one kind of secret
+type Detector struct {
+	// Kind of the secret, used in the redaction marker and the report
+	Kind string
+
+	// Pattern matching the secret, if it has a capture group named "secret" only that group is redacted
+	Pattern *regexp.Regexp
+
+	// Accept optionally filters the matched secret values, e.g. by entropy
+	Accept func(secret string) bool
+}
+
+// BuiltinDetectors returns the detectors that are always enabled
+func BuiltinDetectors() []*Detector {
+	return builtinDetectors
+}
+
+// secretBounds returns the bounds of the sensitive part of a match
+func (d *Detector) secretBounds(match []int) (int, int) {
+	if i := d.Pattern.SubexpIndex(secretGroup); i > 0 && match[2*i] >= 0 {
+		return match[2*i], match[2*i+1]
+	}
+
+	return match[0], match[1]
+}
+
+// Fingerprint returns a stable identifier of a secret which does not reveal its value
+func Fingerprint(kind string, secret []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(kind + ":"))
+	hash.Write(secret)
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+// isGenericSecret checks if the value of a generic assignment looks like a real secret, rather than code, a placeholder or a reference to a variable
+func isGenericSecret(value string) bool {
+	if len(value) < minGenericSecretLength || isCodeReference(value) || isPlaceholder(value) {
+		return false
+	}
+
+	return shannonEntropy(value) >= minGenericSecretEntropy
+}
+
+// isCodeReference checks if the value is a selector chain or a call, e.g. cfg.Password or os.Getenv(, or a plain identifier without digits.
+// A bare token with digits, e.g. the unquoted value of an .env file, is still a possible secret.
+func isCodeReference(value string) bool {
+	if !codeReference.MatchString(value) {
+		return false
+	}
+
+	return strings.ContainsAny(value, ".(") || !strings.ContainsFunc(value, unicode.IsDigit)
+}
+
+// isPlaceholder checks if the value is an example or a template, e.g. changeme123, <your-key>, ${API_KEY}, your_api_key or password1.
+// The words of a placeholder only count as the whole value, so a real value such as MySecretProd9x is not one.
+func isPlaceholder(value string) bool {
+	if strings.ContainsAny(value[:1], "$<{%") {
+		return true
+	}
+
+	lower := strings.ToLower(value)
+	for _, marker := range placeholderMarkers {
+		if strings.Contains(lower, marker) {
+			return true
+		}
+	}
+
+	for _, prefix := range placeholderPrefixes {
+		if strings.HasPrefix(lower, prefix) {
+			return true
+		}
+	}
+
+	// Numbers and punctuation at the end do not make a word a real value, e.g. password123!
+	word := strings.TrimRight(lower, "0123456789!._-")
+	for _, placeholder := range placeholderWords {
+		if word == placeholder {
+			return true
+		}
+	}
+
+	return false
+}
+
+// shannonEntropy calculates the shannon entropy of the value in bits per character
+func shannonEntropy(value string) float64 {
+	if value == "" {
+		return 0
+	}
+
+	frequencies := make(map[rune]int)
+	for _, r := range value {
+		frequencies[r]++
+	}
+
+	var entropy float64
+	length := float64(len([]rune(value)))
+	for _, count := range frequencies {
+		p := float64(count) / length
+		entropy -= p * math.Log2(p)
+	}
+
+	return entropy
+}
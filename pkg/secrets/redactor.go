@@ -0,0 +1,204 @@
+package secrets
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+// customPatternName matches the optional "kind=" prefix of a user supplied pattern
+var customPatternName = regexp.MustCompile(`^([A-Za-z0-9_-]+)=(.+)$`)
+
+// Finding is a single secret found in a file
+type Finding struct {
+	// Kind of the secret
+	Kind string `json:"kind"`
+
+	// Path of the file relative to the base path
+	Path string `json:"path"`
+
+	// Line of the secret, starting from 1
+	Line int `json:"line"`
+
+	// Column of the secret, starting from 1
+	Column int `json:"column"`
+
+	// Fingerprint of the secret
+	Fingerprint string `json:"fingerprint"`
+}
+
+// Redactor replaces secrets in the file contents and keeps a report of what was redacted
+type Redactor struct {
+	// Detectors used to find the secrets
+	Detectors []*Detector
+
+	// Allowlist of accepted secret fingerprints, these are neither redacted nor reported
+	Allowlist map[string]struct{}
+
+	mu       sync.Mutex
+	findings []Finding
+}
+
+// NewRedactor creates a new Redactor with the builtin detectors and the user supplied patterns, a pattern can be prefixed with "kind=" to name it
+func NewRedactor(patterns []string) (*Redactor, error) {
+	detectors := append([]*Detector{}, BuiltinDetectors()...)
+
+	for _, pattern := range patterns {
+		kind := "custom"
+		if match := customPatternName.FindStringSubmatch(pattern); match != nil {
+			kind, pattern = match[1], match[2]
+		}
+
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q: %v", ErrInvalidPattern, pattern, err)
+		}
+
+		detectors = append(detectors, &Detector{
+			Kind:    kind,
+			Pattern: re,
+		})
+	}
+
+	return &Redactor{
+		Detectors: detectors,
+	}, nil
+}
+
+// match is a secret located in the content
+type match struct {
+	detector   *Detector
+	start, end int
+}
+
+// Scan finds the secrets in the content without modifying it
+func (r *Redactor) Scan(path string, content []byte) []Finding {
+	return findings(path, content, r.matches(content))
+}
+
+// Record adds findings to the report, e.g. the findings of a redaction done in an earlier run
+func (r *Redactor) Record(findings ...Finding) {
+	if len(findings) == 0 {
+		return
+	}
+
+	r.mu.Lock()
+	r.findings = append(r.findings, findings...)
+	r.mu.Unlock()
+}
+
+// Apply replaces every secret in the content with a [REDACTED:<kind>] marker and returns the findings without recording them
+func (r *Redactor) Apply(path string, content []byte) ([]byte, []Finding) {
+	matches := r.matches(content)
+	if len(matches) == 0 {
+		return content, nil
+	}
+
+	var redacted bytes.Buffer
+	redacted.Grow(len(content))
+
+	last := 0
+	for _, m := range matches {
+		redacted.Write(content[last:m.start])
+		redacted.WriteString("[REDACTED:" + m.detector.Kind + "]")
+
+		// Keep the line breaks of multi-line secrets, so the line numbers still match the real file
+		redacted.Write(bytes.Repeat([]byte("\n"), bytes.Count(content[m.start:m.end], []byte("\n"))))
+		last = m.end
+	}
+	redacted.Write(content[last:])
+
+	return redacted.Bytes(), findings(path, content, matches)
+}
+
+// Findings returns the findings recorded so far, sorted by path and position
+func (r *Redactor) Findings() []Finding {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	findings := append([]Finding{}, r.findings...)
+	SortFindings(findings)
+
+	return findings
+}
+
+// SortFindings sorts the findings by path and position
+func SortFindings(findings []Finding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Path != findings[j].Path {
+			return findings[i].Path < findings[j].Path
+		}
+		if findings[i].Line != findings[j].Line {
+			return findings[i].Line < findings[j].Line
+		}
+		return findings[i].Column < findings[j].Column
+	})
+}
+
+// matches returns the non overlapping secrets in the content ordered by position
+func (r *Redactor) matches(content []byte) []match {
+	var matches []match
+	for _, detector := range r.Detectors {
+		for _, loc := range detector.Pattern.FindAllSubmatchIndex(content, -1) {
+			start, end := detector.secretBounds(loc)
+			if start == end {
+				continue
+			}
+
+			if detector.Accept != nil && !detector.Accept(string(content[start:end])) {
+				continue
+			}
+
+			if _, ok := r.Allowlist[Fingerprint(detector.Kind, content[start:end])]; ok {
+				continue
+			}
+
+			matches = append(matches, match{detector: detector, start: start, end: end})
+		}
+	}
+
+	// Prefer the earliest and then the longest match, so a private key block wins over a token inside it
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].start != matches[j].start {
+			return matches[i].start < matches[j].start
+		}
+		return matches[i].end > matches[j].end
+	})
+
+	var result []match
+	for _, m := range matches {
+		if len(result) > 0 && m.start < result[len(result)-1].end {
+			continue
+		}
+		result = append(result, m)
+	}
+
+	return result
+}
+
+// findings converts the matches into findings with their line and column
+func findings(path string, content []byte, matches []match) []Finding {
+	var result []Finding
+
+	line, lineStart, offset := 1, 0, 0
+	for _, m := range matches {
+		for ; offset < m.start; offset++ {
+			if content[offset] == '\n' {
+				line++
+				lineStart = offset + 1
+			}
+		}
+
+		result = append(result, Finding{
+			Kind:        m.detector.Kind,
+			Path:        path,
+			Line:        line,
+			Column:      m.start - lineStart + 1,
+			Fingerprint: Fingerprint(m.detector.Kind, content[m.start:m.end]),
+		})
+	}
+
+	return result
+}
@@ -0,0 +1,12 @@
+package server
+
+import "errors"
+
+var (
+	ErrNoAllowedRoots   = errors.New("at least one allowed root is required")
+	ErrInvalidRoot      = errors.New("invalid root")
+	ErrRootNotAllowed   = errors.New("root is outside of the allowed roots")
+	ErrMissingRoot      = errors.New("missing root parameter")
+	ErrServerBusy       = errors.New("too many concurrent requests")
+	ErrMethodNotAllowed = errors.New("method not allowed")
+)
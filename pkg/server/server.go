@@ -0,0 +1,314 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	sourcecollector "github.com/hitesh22rana/sourcecollector/pkg"
+	"github.com/hitesh22rana/sourcecollector/pkg/validators"
+)
+
+// Server serves collections of the directories under its allowed roots over HTTP
+type Server struct {
+	// AllowedRoots are the resolved base directories which can be collected, including their subdirectories
+	AllowedRoots []string
+
+	// Fast collects the files of a request concurrently
+	Fast bool
+
+	// Configure sets the options of the collector of a request, e.g. redaction or size limits, before the parameters of the request are applied
+	Configure func(sc *sourcecollector.SourceCollector) error
+
+	// slots limits the number of requests processed at the same time
+	slots chan struct{}
+}
+
+// NewServer creates a new Server which processes at most maxConcurrent requests at the same time
+func NewServer(allowedRoots []string, maxConcurrent int, fast bool) (*Server, error) {
+	if len(allowedRoots) == 0 {
+		return nil, ErrNoAllowedRoots
+	}
+
+	// Resolve the allowed roots the same way as the requested roots, so they can be compared
+	resolved := make([]string, 0, len(allowedRoots))
+	for _, root := range allowedRoots {
+		path, err := resolvePath(root)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidRoot, err)
+		}
+
+		resolved = append(resolved, path)
+	}
+
+	return &Server{
+		AllowedRoots: resolved,
+		Fast:         fast,
+		slots:        make(chan struct{}, max(maxConcurrent, 1)),
+	}, nil
+}
+
+// Handler returns the HTTP handler of the server
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/collect", s.limit(s.handleCollect))
+	mux.HandleFunc("/tree", s.limit(s.handleTree))
+	mux.HandleFunc("/stats", s.limit(s.handleStats))
+
+	return mux
+}
+
+// limit only allows GET requests and waits for a free slot before handling one, until the request is cancelled
+func (s *Server) limit(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, ErrMethodNotAllowed)
+			return
+		}
+
+		select {
+		case s.slots <- struct{}{}:
+			defer func() { <-s.slots }()
+		case <-r.Context().Done():
+			writeError(w, http.StatusServiceUnavailable, ErrServerBusy)
+			return
+		}
+
+		handler(w, r)
+	}
+}
+
+// handleCollect streams the collection of the requested root
+func (s *Server) handleCollect(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	sc, status, err := s.newCollector(r)
+	if err != nil {
+		writeError(w, status, err)
+		return
+	}
+
+	sourceTree, sourceTreeStructure, err := generateSourceTree(ctx, sc)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	contentType := "text/plain; charset=utf-8"
+	if sc.Format == sourcecollector.FormatJSON {
+		contentType = "application/json"
+	}
+
+	// The status is sent before the files are read, the files which could not be collected are reported in a trailer
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Trailer", "X-Failed-Files")
+	w.WriteHeader(http.StatusOK)
+
+	err = sc.WriteSourceCode(ctx, &flushWriter{w: w}, sourceTree, sourceTreeStructure)
+
+	var collectionErr *sourcecollector.CollectionError
+	switch {
+	case errors.As(err, &collectionErr):
+		w.Header().Set("X-Failed-Files", fmt.Sprint(len(collectionErr.Errors)))
+	case err != nil && ctx.Err() == nil:
+		log.Printf("collect %s: %v", sc.Input, err)
+	}
+}
+
+// handleTree writes the tree structure of the requested root
+func (s *Server) handleTree(w http.ResponseWriter, r *http.Request) {
+	sc, status, err := s.newCollector(r)
+	if err != nil {
+		writeError(w, status, err)
+		return
+	}
+
+	_, sourceTreeStructure, err := generateSourceTree(r.Context(), sc)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if sc.Format == sourcecollector.FormatJSON {
+		writeJSON(w, http.StatusOK, map[string]string{"tree": sourceTreeStructure})
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte(sourceTreeStructure + "\n"))
+}
+
+// handleStats writes the size of every file of the requested root as JSON
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	sc, status, err := s.newCollector(r)
+	if err != nil {
+		writeError(w, status, err)
+		return
+	}
+
+	sourceTree, err := sc.GenerateSourceTree(ctx)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// The files which could not be read are left out of the stats
+	stats, err := sc.Stats(ctx, sourceTree)
+
+	var collectionErr *sourcecollector.CollectionError
+	if err != nil && !errors.As(err, &collectionErr) {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// newCollector makes the collector of a request from its root, include, exclude and format parameters
+func (s *Server) newCollector(r *http.Request) (*sourcecollector.SourceCollector, int, error) {
+	query := r.URL.Query()
+
+	root, err := s.resolveRoot(query.Get("root"))
+	if err != nil {
+		if errors.Is(err, ErrRootNotAllowed) {
+			return nil, http.StatusForbidden, err
+		}
+		return nil, http.StatusBadRequest, err
+	}
+
+	sc, err := sourcecollector.NewSourceCollector(root, "", s.Fast)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	if s.Configure != nil {
+		if err := s.Configure(sc); err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+	}
+
+	if format := query.Get("format"); format != "" {
+		sc.Format, err = sourcecollector.ParseFormat(format)
+		if err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+	}
+
+	if include, exclude := query["include"], query["exclude"]; len(include) > 0 || len(exclude) > 0 {
+		sc.Validator, err = validators.NewPatternValidator(sc.Validator, sc.Input, include, exclude)
+		if err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+	}
+
+	return sc, http.StatusOK, nil
+}
+
+// resolveRoot resolves the requested root, a relative root is relative to the first allowed root.
+// Symbolic links are resolved before the root is checked, so a link cannot point outside of the allowed roots.
+func (s *Server) resolveRoot(root string) (string, error) {
+	if root == "" {
+		return "", ErrMissingRoot
+	}
+
+	path := root
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(s.AllowedRoots[0], path)
+	}
+
+	path, err := resolvePath(path)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidRoot, err)
+	}
+
+	for _, allowedRoot := range s.AllowedRoots {
+		if isWithin(allowedRoot, path) {
+			return path, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrRootNotAllowed, root)
+}
+
+// generateSourceTree generates the source tree of the collector and its structure
+func generateSourceTree(ctx context.Context, sc *sourcecollector.SourceCollector) (*sourcecollector.SourceTree, string, error) {
+	sourceTree, err := sc.GenerateSourceTree(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	sourceTreeStructure, err := sc.GenerateSourceTreeStructure(ctx, sourceTree)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return sourceTree, sourceTreeStructure, nil
+}
+
+// resolvePath makes the path absolute and resolves its symbolic links
+func resolvePath(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	path, err = filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+
+	if !info.IsDir() {
+		return "", fmt.Errorf("%s is not a directory", path)
+	}
+
+	return path, nil
+}
+
+// isWithin checks if the path is the base directory or inside it
+func isWithin(base string, path string) bool {
+	relPath, err := filepath.Rel(base, path)
+	if err != nil {
+		return false
+	}
+
+	return relPath != ".." && !strings.HasPrefix(relPath, ".."+string(filepath.Separator))
+}
+
+// writeJSON writes the value as a JSON response
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+// writeError writes the error as a JSON response
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// flushWriter flushes every write to the client, so the collection is streamed while it is being read
+type flushWriter struct {
+	w http.ResponseWriter
+}
+
+func (fw *flushWriter) Write(p []byte) (int, error) {
+	n, err := fw.w.Write(p)
+	if flusher, ok := fw.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+
+	return n, err
+}
@@ -0,0 +1,216 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	sourcecollector "github.com/hitesh22rana/sourcecollector/pkg"
+	"github.com/hitesh22rana/sourcecollector/pkg/internal/testutil"
+	"github.com/hitesh22rana/sourcecollector/pkg/validators"
+)
+
+// newTestServer creates a server of an allowed root with a project directory, and a directory next to it which is not allowed
+func newTestServer(t *testing.T, maxConcurrent int) (*Server, string, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	allowed := filepath.Join(dir, "allowed")
+	outside := filepath.Join(dir, "outside")
+
+	for _, path := range []string{filepath.Join(allowed, "project"), outside} {
+		if err := os.MkdirAll(path, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(allowed, "project", "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewServer([]string{allowed}, maxConcurrent, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Configure = func(sc *sourcecollector.SourceCollector) error {
+		sc.Validator = testutil.AcceptAll{}
+		return nil
+	}
+
+	return s, s.AllowedRoots[0], outside
+}
+
+func TestResolveRoot(t *testing.T) {
+	s, allowed, outside := newTestServer(t, 1)
+
+	if err := os.Symlink(outside, filepath.Join(allowed, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(allowed, "project"), filepath.Join(filepath.Dir(outside), "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		root string
+		want string
+		err  error
+	}{
+		{name: "relative", root: "project", want: filepath.Join(allowed, "project")},
+		{name: "absolute", root: filepath.Join(allowed, "project"), want: filepath.Join(allowed, "project")},
+		{name: "allowed root", root: allowed, want: allowed},
+		{name: "link into an allowed root", root: filepath.Join(filepath.Dir(outside), "link"), want: filepath.Join(allowed, "project")},
+		{name: "missing", root: "", err: ErrMissingRoot},
+		{name: "outside", root: outside, err: ErrRootNotAllowed},
+		{name: "relative escape", root: "../outside", err: ErrRootNotAllowed},
+		{name: "link out of an allowed root", root: "escape", err: ErrRootNotAllowed},
+		{name: "not found", root: "missing", err: ErrInvalidRoot},
+		{name: "file", root: "project/main.go", err: ErrInvalidRoot},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := s.resolveRoot(test.root)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("error %v, want %v", err, test.err)
+			}
+			if got != test.want {
+				t.Errorf("root %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestIsWithin(t *testing.T) {
+	base := filepath.FromSlash("/srv/code")
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{path: "/srv/code", want: true},
+		{path: "/srv/code/project", want: true},
+		{path: "/srv/code/..project", want: true},
+		{path: "/srv", want: false},
+		{path: "/srv/code-other", want: false},
+		{path: "/srv/other/code", want: false},
+	}
+
+	for _, test := range tests {
+		if got := isWithin(base, filepath.FromSlash(test.path)); got != test.want {
+			t.Errorf("isWithin(%q, %q) = %t, want %t", base, test.path, got, test.want)
+		}
+	}
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	s, _, _ := newTestServer(t, 1)
+	handler := s.Handler()
+
+	// Take the only slot, as a running request would
+	s.slots <- struct{}{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/tree?root=project", nil).WithContext(ctx))
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("status %d while the slot is taken, want %d", recorder.Code, http.StatusServiceUnavailable)
+	}
+
+	<-s.slots
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/tree?root=project", nil))
+	if recorder.Code != http.StatusOK {
+		t.Errorf("status %d once the slot is free, want %d", recorder.Code, http.StatusOK)
+	}
+	if len(s.slots) != 0 {
+		t.Errorf("%d slots still taken after the request", len(s.slots))
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	s, _, _ := newTestServer(t, 1)
+
+	recorder := httptest.NewRecorder()
+	s.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/collect?root=project", nil))
+	if recorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("status %d, want %d", recorder.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestCollectFailedFilesTrailer(t *testing.T) {
+	s, allowed, _ := newTestServer(t, 1)
+
+	// A socket is listed like a file but cannot be read, even by root
+	listener, err := net.Listen("unix", filepath.Join(allowed, "project", "app.sock"))
+	if err != nil {
+		t.Skipf("unix sockets are not supported: %v", err)
+	}
+	defer listener.Close()
+
+	httpServer := httptest.NewServer(s.Handler())
+	defer httpServer.Close()
+
+	resp, err := http.Get(httpServer.URL + "/collect?root=project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d, want %d: %s", resp.StatusCode, http.StatusOK, body)
+	}
+	if !strings.Contains(string(body), "package main") {
+		t.Errorf("the readable file is missing from the collection:\n%s", body)
+	}
+
+	// The trailer is only known once the body was read
+	if got := resp.Trailer.Get("X-Failed-Files"); got != "1" {
+		t.Errorf("X-Failed-Files %q, want 1", got)
+	}
+}
+
+func TestRequestPatternsNarrowSelection(t *testing.T) {
+	s, allowed, _ := newTestServer(t, 1)
+
+	for _, name := range []string{"README.md", "main_test.go"} {
+		if err := os.WriteFile(filepath.Join(allowed, "project", name), []byte(name+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The server excludes the markdown files, as with --exclude
+	s.Configure = func(sc *sourcecollector.SourceCollector) error {
+		var err error
+		sc.Validator, err = validators.NewPatternValidator(testutil.AcceptAll{}, sc.Input, nil, []string{"**/*.md"})
+		return err
+	}
+
+	recorder := httptest.NewRecorder()
+	s.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/collect?root=project&include=**/*.md&include=**/*.go&exclude=**/*_test.go", nil))
+	body := recorder.Body.String()
+
+	if !strings.Contains(body, "Path: project/main.go") {
+		t.Errorf("main.go is missing from the collection:\n%s", body)
+	}
+	for _, path := range []string{"project/README.md", "project/main_test.go"} {
+		if strings.Contains(body, "Path: "+path) {
+			t.Errorf("%s is in the collection:\n%s", path, body)
+		}
+	}
+}
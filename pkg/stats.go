@@ -0,0 +1,103 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/hitesh22rana/sourcecollector/pkg/cache"
+)
+
+// FileStats is the size of a single collected file
+type FileStats struct {
+	// Path of the file relative to the base path
+	Path string `json:"path"`
+
+	// Bytes of the file content as it is written to the output
+	Bytes int `json:"bytes"`
+
+	// Lines of the file content as it is written to the output
+	Lines int `json:"lines"`
+
+	// Tokens is the estimated number of LLM tokens of the file
+	Tokens int `json:"tokens"`
+
+	// SizeStatus of the file, set if it would be truncated or skipped because of the size limit
+	SizeStatus SizeStatus `json:"sizeStatus,omitempty"`
+}
+
+// Stats is the size of a whole collection
+type Stats struct {
+	// Files sorted by size, largest first
+	Files []FileStats `json:"files"`
+
+	// TotalBytes of all the files
+	TotalBytes int `json:"totalBytes"`
+
+	// TotalLines of all the files
+	TotalLines int `json:"totalLines"`
+
+	// TotalTokens is the estimated number of LLM tokens of all the files
+	TotalTokens int `json:"totalTokens"`
+}
+
+// Stats measures the files which would be collected from the source tree as they end up in the output, after the transformations, without writing anything.
+// The files which cannot be read are left out and returned as a *CollectionError along with the stats.
+func (sc *SourceCollector) Stats(ctx context.Context, sourceTree *SourceTree) (*Stats, error) {
+	// Check if the source tree is nil
+	if sourceTree == nil {
+		return nil, ErrCollectStats
+	}
+
+	var (
+		mu         sync.Mutex
+		stats      = &Stats{Files: []FileStats{}}
+		fileErrors []*FileError
+	)
+
+	sc.transformSourceFiles(ctx, sourceTree, func(node SourceNode, relPath string, blob *cache.Blob, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if err != nil {
+			fileErrors = append(fileErrors, &FileError{Path: relPath, Err: err})
+			return
+		}
+
+		// Leave out the files the size limit would skip
+		if blob.Skipped {
+			return
+		}
+
+		fileStats := FileStats{
+			Path:       relPath,
+			Bytes:      len(blob.Data),
+			Lines:      countLines(blob.Data),
+			Tokens:     blob.Tokens,
+			SizeStatus: blobSizeStatus(blob),
+		}
+
+		stats.Files = append(stats.Files, fileStats)
+		stats.TotalBytes += fileStats.Bytes
+		stats.TotalLines += fileStats.Lines
+		stats.TotalTokens += fileStats.Tokens
+	})
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCollectStats, err)
+	}
+
+	sort.SliceStable(stats.Files, func(i, j int) bool {
+		if stats.Files[i].Bytes != stats.Files[j].Bytes {
+			return stats.Files[i].Bytes > stats.Files[j].Bytes
+		}
+		return stats.Files[i].Path < stats.Files[j].Path
+	})
+
+	if len(fileErrors) > 0 {
+		return stats, newCollectionError(fileErrors)
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,251 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/hitesh22rana/sourcecollector/pkg/validators"
+)
+
+// TreePlacement decides where the tree structure goes in a streamed output
+type TreePlacement string
+
+const (
+	// TreePlacementStart writes the tree before the files, like a regular collection. The files are streamed to a temporary file first and copied after the tree.
+	TreePlacementStart TreePlacement = "start"
+
+	// TreePlacementEnd writes the tree after the files, so the output is written in a single pass
+	TreePlacementEnd TreePlacement = "end"
+)
+
+// ParseTreePlacement parses where a streamed output places the tree structure
+func ParseTreePlacement(placement string) (TreePlacement, error) {
+	switch p := TreePlacement(placement); p {
+	case TreePlacementStart, TreePlacementEnd:
+		return p, nil
+	default:
+		return "", fmt.Errorf("%w: %q, expected start or end", ErrInvalidTreePlacement, placement)
+	}
+}
+
+// walkedPath is a path found by the streaming walk, the tree structure is assembled from them once the walk is done
+type walkedPath struct {
+	path       string
+	dir        bool
+	sizeStatus SizeStatus
+	link       string
+}
+
+// StreamSourceCode walks the input directory and writes the files to the output while they are found, without building the source tree first.
+// A parallel walker feeds the read workers directly, so the order of the files varies between runs.
+// The tree structure is assembled from the walked paths and written where the placement says, tree annotations read every file a second time.
+func (sc *SourceCollector) StreamSourceCode(ctx context.Context, placement TreePlacement) error {
+	// Check if there is an output to save to
+	if sc.Output == "" {
+		return ErrInvalidOutputPath
+	}
+
+	file, err := sc.createOutputFile()
+	if err != nil {
+		return err
+	}
+	defer file.discard()
+
+	// Stream the files to a second temporary file, so the tree can be written before them
+	var contents io.ReadWriteSeeker = file
+	if placement == TreePlacementStart {
+		tmp, err := os.CreateTemp(filepath.Dir(sc.Output), "."+filepath.Base(sc.Output)+".*.tmp")
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrOpenOutputFile, err)
+		}
+		defer os.Remove(tmp.Name())
+		defer tmp.Close()
+
+		contents = tmp
+	}
+
+	formatter := sc.formatter()
+
+	if placement == TreePlacementEnd {
+		if _, err := io.WriteString(contents, formatter.header("")); err != nil {
+			return fmt.Errorf("%w: %v", ErrWriteOutputFile, err)
+		}
+	}
+
+	// Forget the paths skipped by a previous walk
+	sc.skippedMu.Lock()
+	sc.skipped = nil
+	sc.skippedMu.Unlock()
+	sc.duplicates = nil
+
+	var (
+		walkedMu sync.Mutex
+		walked   []walkedPath
+	)
+
+	collectErr := sc.writeSourceFiles(ctx, contents, formatter, func(ctx context.Context, emit func(node SourceNode)) {
+		sc.walk(ctx, func(entry walkedPath) {
+			walkedMu.Lock()
+			walked = append(walked, entry)
+			walkedMu.Unlock()
+
+			if !entry.dir {
+				emit(SourceNode{Name: extractName(entry.path), Path: entry.path, SizeStatus: entry.sizeStatus})
+			}
+		})
+	})
+
+	// Keep the previous output if the collection was cancelled or failed
+	if collectErr != nil && (!isCollectionError(collectErr) || sc.OnError == ErrorPolicyFail) {
+		return collectErr
+	}
+
+	sourceTreeStructure, err := sc.GenerateSourceTreeStructure(ctx, sc.assembleSourceTree(walked))
+	if err != nil {
+		return err
+	}
+
+	if placement == TreePlacementStart {
+		if _, err := io.WriteString(file, formatter.header(sourceTreeStructure)); err != nil {
+			return fmt.Errorf("%w: %v", ErrWriteOutputFile, err)
+		}
+
+		// Second pass, copy the streamed files after the tree
+		if _, err := contents.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("%w: %v", ErrWriteOutputFile, err)
+		}
+		if _, err := io.Copy(file, contents); err != nil {
+			return fmt.Errorf("%w: %v", ErrWriteOutputFile, err)
+		}
+
+		sourceTreeStructure = ""
+	}
+
+	if _, err := io.WriteString(file, formatter.footer(sourceTreeStructure)); err != nil {
+		return fmt.Errorf("%w: %v", ErrWriteOutputFile, err)
+	}
+
+	// Replace the output with the complete collection
+	if err := file.commit(); err != nil {
+		return err
+	}
+
+	return collectErr
+}
+
+// walk walks the input directory with up to sc.MaxConcurrency goroutines reading directories at the same time and calls fn for every path which is not ignored.
+// fn is called concurrently, the walk stops as soon as the context is done.
+func (sc *SourceCollector) walk(ctx context.Context, fn func(entry walkedPath)) {
+	// The input itself is checked like every other path
+	if ignored, reason := validators.IsIgnoredEntry(sc.Validator, sc.Input, true); ignored {
+		sc.recordSkipped(sc.Input, reason)
+		return
+	}
+
+	// Bound the number of goroutines reading directories besides this one, a subdirectory is read in place when no slot is free
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, max(sc.MaxConcurrency-1, 0))
+	root := sc.symlinkRoot()
+
+	var walkDir func(ancestors *walkAncestor)
+	walkDir = func(ancestors *walkAncestor) {
+		path := ancestors.path
+		if ctx.Err() != nil {
+			return
+		}
+
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return
+		}
+
+		for _, entry := range entries {
+			if ctx.Err() != nil {
+				return
+			}
+
+			entryPath := filepath.Join(path, entry.Name())
+			if entryPath == sc.Output {
+				continue
+			}
+
+			// Handle symbolic links like the regular walk does
+			e, ok := sc.resolveEntry(entryPath, entry, root, ancestors)
+			if !ok {
+				continue
+			}
+
+			if ignored, reason := validators.IsIgnoredEntry(sc.Validator, entryPath, e.isDir); ignored {
+				sc.recordSkipped(entryPath, reason)
+				continue
+			}
+
+			if e.isDir {
+				fn(walkedPath{path: entryPath, dir: true, link: e.link})
+
+				select {
+				case slots <- struct{}{}:
+					wg.Add(1)
+					go func(ancestors *walkAncestor) {
+						defer wg.Done()
+						defer func() { <-slots }()
+
+						walkDir(ancestors)
+					}(&walkAncestor{path: entryPath, parent: ancestors})
+				default:
+					walkDir(&walkAncestor{path: entryPath, parent: ancestors})
+				}
+				continue
+			}
+
+			var sizeStatus SizeStatus
+			if sc.SizeLimit != nil {
+				sizeStatus = sc.sizeStatus(entryPath, e.size())
+			}
+
+			fn(walkedPath{path: entryPath, sizeStatus: sizeStatus, link: e.link})
+		}
+	}
+
+	walkDir(&walkAncestor{path: sc.Input})
+	wg.Wait()
+}
+
+// assembleSourceTree builds the source tree from the walked paths, it holds the same entries as the tree of GenerateSourceTree
+func (sc *SourceCollector) assembleSourceTree(walked []walkedPath) *SourceTree {
+	// A parent path sorts before the paths inside it
+	sort.Slice(walked, func(i, j int) bool {
+		return walked[i].path < walked[j].path
+	})
+
+	root := &SourceTree{
+		Root:  &SourceNode{Name: extractName(sc.Input), Path: sc.Input},
+		Nodes: []*SourceTree{},
+	}
+
+	directories := map[string]*SourceTree{sc.Input: root}
+	for _, entry := range walked {
+		parent, ok := directories[filepath.Dir(entry.path)]
+		if !ok {
+			continue
+		}
+
+		node := &SourceTree{
+			Root: &SourceNode{Name: extractName(entry.path), Path: entry.path, SizeStatus: entry.sizeStatus, LinkTarget: entry.link},
+		}
+
+		if entry.dir {
+			node.Nodes = []*SourceTree{}
+			directories[entry.path] = node
+		}
+
+		parent.Nodes = append(parent.Nodes, node)
+	}
+
+	return root
+}
@@ -0,0 +1,96 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hitesh22rana/sourcecollector/pkg/internal/testutil"
+)
+
+// streamFiles writes the files into an input directory and streams them to an output file next to it
+func streamFiles(t *testing.T, files map[string]string, format Format, placement TreePlacement) []byte {
+	t.Helper()
+
+	input := testutil.WriteFiles(t, files)
+	output := filepath.Join(filepath.Dir(input), "output.txt")
+	sc, err := NewSourceCollector(input, output, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc.Validator = testutil.AcceptAll{}
+	sc.Format = format
+
+	if err := sc.StreamSourceCode(context.Background(), placement); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+}
+
+// parseSorted parses the bundle with its files sorted by path, as a stream writes them in any order
+func parseSorted(t *testing.T, data []byte) []BundleFile {
+	t.Helper()
+
+	files, err := ParseBundle(data)
+	if err != nil {
+		t.Fatalf("%v\n%s", err, data)
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+
+	return files
+}
+
+func TestStreamSourceCode(t *testing.T) {
+	for _, format := range []Format{FormatText, FormatJSON} {
+		want := parseSorted(t, collect(t, roundTripFiles, format))
+
+		for _, placement := range []TreePlacement{TreePlacementStart, TreePlacementEnd} {
+			t.Run(string(format)+"/"+string(placement), func(t *testing.T) {
+				data := streamFiles(t, roundTripFiles, format, placement)
+
+				if got := parseSorted(t, data); !reflect.DeepEqual(got, want) {
+					t.Errorf("streamed files %+v, want %+v", got, want)
+				}
+
+				if format != FormatText {
+					return
+				}
+
+				// The tree goes where the placement says
+				header := "Source code files structure\n\n"
+				if starts := strings.HasPrefix(string(data), header); starts != (placement == TreePlacementStart) {
+					t.Errorf("output starts with the tree: %t, want %t", starts, placement == TreePlacementStart)
+				}
+				if strings.Count(string(data), header) != 1 {
+					t.Errorf("output has %d trees, want 1", strings.Count(string(data), header))
+				}
+			})
+		}
+	}
+}
+
+func TestParseTreePlacement(t *testing.T) {
+	for _, placement := range []TreePlacement{TreePlacementStart, TreePlacementEnd} {
+		if got, err := ParseTreePlacement(string(placement)); err != nil || got != placement {
+			t.Errorf("ParseTreePlacement(%q) = %q, %v", placement, got, err)
+		}
+	}
+
+	if _, err := ParseTreePlacement("middle"); !errors.Is(err, ErrInvalidTreePlacement) {
+		t.Errorf("error %v, want %v", err, ErrInvalidTreePlacement)
+	}
+}
@@ -0,0 +1,210 @@
+package pkg
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hitesh22rana/sourcecollector/pkg/validators"
+)
+
+// SymlinkPolicy decides how the walk handles symbolic links
+type SymlinkPolicy string
+
+const (
+	// SymlinkSkip leaves all the symbolic links out
+	SymlinkSkip SymlinkPolicy = "skip"
+
+	// SymlinkFollow follows the symbolic links wherever they point to
+	SymlinkFollow SymlinkPolicy = "follow"
+
+	// SymlinkFollowWithinRoot only follows the symbolic links which point inside the input directory
+	SymlinkFollowWithinRoot SymlinkPolicy = "follow-within-root"
+)
+
+// ParseSymlinkPolicy parses the symlink policy from its name
+func ParseSymlinkPolicy(policy string) (SymlinkPolicy, error) {
+	switch p := SymlinkPolicy(policy); p {
+	case SymlinkSkip, SymlinkFollow, SymlinkFollowWithinRoot:
+		return p, nil
+	default:
+		return "", fmt.Errorf("%w: %q, expected skip, follow or follow-within-root", ErrInvalidSymlinkPolicy, policy)
+	}
+}
+
+// walkEntry is an entry of a directory listing, resolved by the symlink policy
+type walkEntry struct {
+	path  string
+	isDir bool
+
+	// link is the target of the symbolic link, empty for regular entries
+	link string
+
+	// size loads the size of a file, it is only called when a size limit is set
+	size func() int64
+}
+
+// walkAncestor is a directory on the way from the input to the directory being read, used to detect symbolic link cycles
+type walkAncestor struct {
+	path   string
+	parent *walkAncestor
+}
+
+// symlinkRoot returns the input directory with its symbolic links resolved, to tell if a link points inside it
+func (sc *SourceCollector) symlinkRoot() string {
+	root, err := filepath.EvalSymlinks(sc.Input)
+	if err != nil {
+		return sc.Input
+	}
+
+	return root
+}
+
+// resolveEntry resolves the entry of a directory listing by the symlink policy, ok is false if it is left out.
+// Only symbolic links are stat'ed, a skipped link is recorded with the reason why.
+func (sc *SourceCollector) resolveEntry(path string, entry fs.DirEntry, root string, ancestors *walkAncestor) (e walkEntry, ok bool) {
+	if entry.Type()&fs.ModeSymlink == 0 {
+		return walkEntry{path: path, isDir: entry.IsDir(), size: entrySize(entry)}, true
+	}
+
+	link, err := os.Readlink(path)
+	if err != nil {
+		return walkEntry{}, false
+	}
+
+	if sc.Symlinks == SymlinkSkip {
+		sc.recordSkipped(path, symlinkReason(link, "symbolic links are skipped"))
+		return walkEntry{}, false
+	}
+
+	// A broken link is left out like a file which no longer exists
+	info, err := os.Stat(path)
+	if err != nil {
+		return walkEntry{}, false
+	}
+
+	if sc.Symlinks == SymlinkFollowWithinRoot {
+		target, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			return walkEntry{}, false
+		}
+
+		if !isWithin(root, target) {
+			sc.recordSkipped(path, symlinkReason(link, "points outside the input directory"))
+			return walkEntry{}, false
+		}
+	}
+
+	// A directory which is its own ancestor would be walked forever
+	if info.IsDir() {
+		if ancestor := findAncestor(info, ancestors); ancestor != "" {
+			relPath, _ := filepath.Rel(sc.BasePath, ancestor)
+			sc.recordSkipped(path, symlinkReason(link, "cycle back to "+filepath.ToSlash(relPath)))
+			return walkEntry{}, false
+		}
+	}
+
+	return walkEntry{
+		path:  path,
+		isDir: info.IsDir(),
+		link:  link,
+		size:  info.Size,
+	}, true
+}
+
+// symlinkPathReason tells why a path relative to the input is left out by the symlink policy, nil means it is not.
+// Every link on the way is checked like the walk does, which never reaches such a path, so it cannot be read directly either. The error is set if the path cannot be resolved.
+func (sc *SourceCollector) symlinkPathReason(relPath string) (*validators.Reason, error) {
+	// A broken link or a missing file is not found, rather than left out
+	if _, err := filepath.EvalSymlinks(filepath.Join(sc.Input, relPath)); err != nil {
+		return nil, err
+	}
+
+	root := sc.symlinkRoot()
+	ancestors := &walkAncestor{path: sc.Input}
+	for _, part := range strings.Split(relPath, string(filepath.Separator)) {
+		if part == "." {
+			continue
+		}
+		path := filepath.Join(ancestors.path, part)
+
+		info, err := os.Lstat(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if info.Mode()&fs.ModeSymlink != 0 {
+			link, _ := os.Readlink(path)
+			if sc.Symlinks == SymlinkSkip {
+				reason := symlinkReason(link, "symbolic links are skipped")
+				return &reason, nil
+			}
+
+			if sc.Symlinks == SymlinkFollowWithinRoot {
+				target, err := filepath.EvalSymlinks(path)
+				if err != nil {
+					return nil, err
+				}
+
+				if !isWithin(root, target) {
+					reason := symlinkReason(link, "points outside the input directory")
+					return &reason, nil
+				}
+			}
+
+			// A directory which is its own ancestor is never walked into
+			if info, err = os.Stat(path); err == nil && info.IsDir() {
+				if ancestor := findAncestor(info, ancestors); ancestor != "" {
+					ancestorPath, _ := filepath.Rel(sc.BasePath, ancestor)
+					reason := symlinkReason(link, "cycle back to "+filepath.ToSlash(ancestorPath))
+					return &reason, nil
+				}
+			}
+		}
+
+		ancestors = &walkAncestor{path: path, parent: ancestors}
+	}
+
+	return nil, nil
+}
+
+// findAncestor returns the path of the ancestor which is the same directory as info, compared by device and inode
+func findAncestor(info fs.FileInfo, ancestors *walkAncestor) string {
+	for ancestor := ancestors; ancestor != nil; ancestor = ancestor.parent {
+		ancestorInfo, err := os.Stat(ancestor.path)
+		if err == nil && os.SameFile(info, ancestorInfo) {
+			return ancestor.path
+		}
+	}
+
+	return ""
+}
+
+// isWithin reports whether the path is the root or inside it
+func isWithin(root string, path string) bool {
+	relPath, err := filepath.Rel(root, path)
+	return err == nil && relPath != ".." && !strings.HasPrefix(relPath, ".."+string(filepath.Separator))
+}
+
+// symlinkReason is the reason why a symbolic link is left out
+func symlinkReason(link string, detail string) validators.Reason {
+	return validators.Reason{
+		Rule:   validators.RuleSymlink,
+		Source: link,
+		Detail: detail,
+	}
+}
+
+// entrySize returns a function loading the size of the file of a directory listing
+func entrySize(entry fs.DirEntry) func() int64 {
+	return func() int64 {
+		info, err := entry.Info()
+		if err != nil {
+			return 0
+		}
+
+		return info.Size()
+	}
+}
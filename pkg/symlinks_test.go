@@ -0,0 +1,141 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hitesh22rana/sourcecollector/pkg/internal/testutil"
+)
+
+// symlinkFixture makes an input directory with a loop, a link inside it and a link outside of it, and returns the input
+func symlinkFixture(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input")
+	testutil.WriteFilesIn(t, dir, map[string]string{
+		"input/src/a.go": "package a\n",
+		"outside/o.go":   "package o\n",
+	})
+
+	for link, target := range map[string]string{
+		"src/loop": "..",
+		"alias":    "src",
+		"ext":      "../outside",
+	} {
+		if err := os.Symlink(target, filepath.Join(input, link)); err != nil {
+			t.Skipf("symbolic links are not supported: %v", err)
+		}
+	}
+
+	return input
+}
+
+func TestSymlinkPolicies(t *testing.T) {
+	input := symlinkFixture(t)
+
+	tests := []struct {
+		policy SymlinkPolicy
+		want   string
+	}{
+		{SymlinkSkip, `input
+└── src
+    └── a.go
+`},
+		{SymlinkFollowWithinRoot, `input
+├── alias -> src
+│   └── a.go
+└── src
+    └── a.go
+`},
+		{SymlinkFollow, `input
+├── alias -> src
+│   └── a.go
+├── ext -> ../outside
+│   └── o.go
+└── src
+    └── a.go
+`},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			sc, err := NewSourceCollector(input, "", true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sc.Validator = testutil.AcceptAll{}
+			sc.Symlinks = tt.policy
+
+			ctx := context.Background()
+			tree, err := sc.GenerateSourceTree(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := sc.RenderSourceTree(ctx, tree, TreeFormatText)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != tt.want {
+				t.Errorf("tree:\n%s\nwant:\n%s", got, tt.want)
+			}
+
+			// The loop is never followed, the policies which follow links report it as a cycle
+			if tt.policy == SymlinkSkip {
+				return
+			}
+
+			var loopDetail *string
+			for _, skipped := range sc.SkippedPaths() {
+				if skipped.Path == filepath.Join("input", "src", "loop") {
+					loopDetail = &skipped.Detail
+				}
+			}
+
+			switch {
+			case loopDetail == nil:
+				t.Errorf("the loop is not in the skipped paths %+v", sc.SkippedPaths())
+			case !strings.HasPrefix(*loopDetail, "cycle"):
+				t.Errorf("loop skipped for %q, want a cycle", *loopDetail)
+			}
+		})
+	}
+}
+
+func TestReadSourceFileOutsideRoot(t *testing.T) {
+	input := symlinkFixture(t)
+
+	sc, err := NewSourceCollector(input, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc.Validator = testutil.AcceptAll{}
+
+	if _, err := sc.ReadSourceFile("input/alias/a.go"); err != nil {
+		t.Errorf("link within the root: %v", err)
+	}
+
+	if _, err := sc.ReadSourceFile("input/ext/o.go"); !errors.Is(err, ErrPathIgnored) {
+		t.Errorf("link outside the root: error %v, want %v", err, ErrPathIgnored)
+	}
+
+	// A missing target is not found, rather than outside of the input
+	if err := os.Symlink("missing.go", filepath.Join(input, "src", "broken.go")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sc.ReadSourceFile("input/src/broken.go"); !errors.Is(err, ErrInvalidInputPath) {
+		t.Errorf("broken link: error %v, want %v", err, ErrInvalidInputPath)
+	}
+
+	reason, err := sc.symlinkPathReason(filepath.Join("src", "missing.go"))
+	if !errors.Is(err, fs.ErrNotExist) || reason != nil {
+		t.Errorf("missing path: reason %v, error %v, want a not found error", reason, err)
+	}
+}
@@ -0,0 +1,145 @@
+package pkg
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+var (
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// readSourceFile copies the whole file into memory independent of its line lengths, the transforms and the fence of the output work on the whole content.
+// UTF-8 content is copied unchanged, with its line endings and byte order mark. UTF-16 and Latin-1 content is transcoded to UTF-8 while it is copied, which is reported as transcoded.
+func readSourceFile(path string) (content []byte, transcoded bool, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, false, err
+	}
+	defer file.Close()
+
+	var buffer bytes.Buffer
+	if info, err := file.Stat(); err == nil {
+		buffer.Grow(int(info.Size()))
+	}
+
+	reader := bufio.NewReader(file)
+
+	// UTF-16 is detected by its byte order mark, the content without one is UTF-8 unless it is not valid
+	bom, _ := reader.Peek(len(bomUTF16LE))
+	switch {
+	case bytes.Equal(bom, bomUTF16LE):
+		reader.Discard(len(bomUTF16LE))
+		err = decodeUTF16(reader, &buffer, binary.LittleEndian)
+		transcoded = true
+	case bytes.Equal(bom, bomUTF16BE):
+		reader.Discard(len(bomUTF16BE))
+		err = decodeUTF16(reader, &buffer, binary.BigEndian)
+		transcoded = true
+	default:
+		_, err = io.Copy(&buffer, reader)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	if !transcoded && !utf8.Valid(buffer.Bytes()) {
+		return decodeLatin1(buffer.Bytes()), true, nil
+	}
+
+	return buffer.Bytes(), transcoded, nil
+}
+
+// decodeLatin1 transcodes Latin-1 content, every byte maps to the unicode code point of the same value
+func decodeLatin1(content []byte) []byte {
+	var decoded bytes.Buffer
+	decoded.Grow(len(content) + len(content)/4)
+	for _, b := range content {
+		decoded.WriteRune(rune(b))
+	}
+
+	return decoded.Bytes()
+}
+
+// decodeUTF16 transcodes UTF-16 content without the byte order mark, unpaired surrogates are replaced and a trailing odd byte is dropped
+func decodeUTF16(reader *bufio.Reader, text *bytes.Buffer, order binary.ByteOrder) error {
+	var unit [2]byte
+	var surrogate rune
+
+	for {
+		if _, err := io.ReadFull(reader, unit[:]); err != nil {
+			if surrogate != 0 {
+				text.WriteRune(utf8.RuneError)
+			}
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return nil
+			}
+			return err
+		}
+
+		r := rune(order.Uint16(unit[:]))
+		if surrogate != 0 {
+			if decoded := utf16.DecodeRune(surrogate, r); decoded != utf8.RuneError {
+				text.WriteRune(decoded)
+				surrogate = 0
+				continue
+			}
+
+			text.WriteRune(utf8.RuneError)
+			surrogate = 0
+		}
+
+		// A high surrogate is decoded with the unit after it
+		if r >= 0xD800 && r < 0xDC00 {
+			surrogate = r
+			continue
+		}
+
+		text.WriteRune(r)
+	}
+}
+
+// truncateLines cuts every line longer than maxLineLength characters and marks how much of it was dropped
+func truncateLines(content []byte, maxLineLength int) []byte {
+	if maxLineLength <= 0 {
+		return content
+	}
+
+	var truncated bytes.Buffer
+	truncated.Grow(len(content))
+
+	for len(content) > 0 {
+		line := content
+		if i := bytes.IndexByte(content, '\n'); i >= 0 {
+			line = content[:i]
+		}
+		content = content[len(line):]
+
+		// Find the byte offset of the first character beyond the limit
+		cut, chars := 0, 0
+		for cut < len(line) && chars < maxLineLength {
+			_, size := utf8.DecodeRune(line[cut:])
+			cut += size
+			chars++
+		}
+
+		truncated.Write(line[:cut])
+		if cut < len(line) {
+			fmt.Fprintf(&truncated, " … [truncated %d bytes]", len(line)-cut)
+		}
+
+		if len(content) > 0 {
+			truncated.WriteByte('\n')
+			content = content[1:]
+		}
+	}
+
+	return truncated.Bytes()
+}
@@ -0,0 +1,70 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadSourceFile(t *testing.T) {
+	tests := []struct {
+		name           string
+		content        []byte
+		want           string
+		wantTranscoded bool
+	}{
+		{
+			name:    "utf-8 with crlf",
+			content: []byte("héllo\r\nworld\r\n"),
+			want:    "héllo\r\nworld\r\n",
+		},
+		{
+			name:    "utf-8 bom without final newline",
+			content: []byte("\xEF\xBB\xBFa\rb"),
+			want:    "\xEF\xBB\xBFa\rb",
+		},
+		{
+			name:           "latin-1 after valid lines",
+			content:        []byte("é ok\r\ncaf\xE9\r\n"),
+			want:           "Ã© ok\r\ncafé\r\n",
+			wantTranscoded: true,
+		},
+		{
+			name:           "utf-16le with surrogate pair",
+			content:        []byte{0xFF, 0xFE, 'h', 0, 'i', 0, 0x3D, 0xD8, 0x00, 0xDE, '\r', 0, '\n', 0},
+			want:           "hi😀\r\n",
+			wantTranscoded: true,
+		},
+		{
+			name:           "utf-16be with unpaired surrogate and odd byte",
+			content:        []byte{0xFE, 0xFF, 0, 'a', 0xD8, 0x3D, 0, 'b', 0},
+			want:           "a�b",
+			wantTranscoded: true,
+		},
+		{
+			name:    "empty",
+			content: nil,
+			want:    "",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "file")
+			if err := os.WriteFile(path, test.content, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			got, transcoded, err := readSourceFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+			if transcoded != test.wantTranscoded {
+				t.Errorf("transcoded %t, want %t", transcoded, test.wantTranscoded)
+			}
+		})
+	}
+}
@@ -0,0 +1,743 @@
+package pkg
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/hitesh22rana/sourcecollector/pkg/validators"
+)
+
+// TreeOrder is the order of the entries of a directory in the tree structure
+type TreeOrder string
+
+const (
+	// TreeOrderAlphabetical sorts the entries by their name, byte by byte
+	TreeOrderAlphabetical TreeOrder = "alpha"
+
+	// TreeOrderNatural sorts the entries by their name ignoring case, with the numbers in them compared by value (file2 before file10)
+	TreeOrderNatural TreeOrder = "natural"
+)
+
+// TreeAnnotation is a measure shown next to every entry of the tree structure, directories show the total of their files
+type TreeAnnotation string
+
+const (
+	TreeAnnotationSize   TreeAnnotation = "size"
+	TreeAnnotationLines  TreeAnnotation = "lines"
+	TreeAnnotationTokens TreeAnnotation = "tokens"
+)
+
+// TreeOptions configure how the tree structure is rendered
+type TreeOptions struct {
+	// Order of the entries of a directory, directories always come before files
+	Order TreeOrder
+
+	// ASCII draws the tree with ASCII characters only, for terminals without Unicode
+	ASCII bool
+
+	// Annotations shown next to every entry
+	Annotations []TreeAnnotation
+
+	// MaxDepth is the deepest level of entries shown below the root, 0 means unlimited
+	MaxDepth int
+
+	// MaxChildren is the number of entries shown per directory, the rest are summed up in one line, 0 means unlimited
+	MaxChildren int
+
+	// ShowExcluded shows the entries excluded from the collection, marked with the rule which excluded them
+	ShowExcluded bool
+}
+
+// TreeFormat is the format of a rendered tree structure
+type TreeFormat string
+
+const (
+	TreeFormatText     TreeFormat = "text"
+	TreeFormatJSON     TreeFormat = "json"
+	TreeFormatMarkdown TreeFormat = "markdown"
+)
+
+// ParseTreeOrder parses the name of a tree order
+func ParseTreeOrder(name string) (TreeOrder, error) {
+	switch order := TreeOrder(strings.ToLower(name)); order {
+	case TreeOrderAlphabetical, TreeOrderNatural:
+		return order, nil
+	default:
+		return "", fmt.Errorf("%w: unknown order %q", ErrInvalidTreeOptions, name)
+	}
+}
+
+// ParseTreeFormat parses the name of a tree format
+func ParseTreeFormat(name string) (TreeFormat, error) {
+	switch format := TreeFormat(strings.ToLower(name)); format {
+	case TreeFormatText, TreeFormatJSON, TreeFormatMarkdown:
+		return format, nil
+	case "md":
+		return TreeFormatMarkdown, nil
+	default:
+		return "", fmt.Errorf("%w: unknown format %q", ErrInvalidTreeOptions, name)
+	}
+}
+
+// ParseTreeAnnotations parses the names of the tree annotations
+func ParseTreeAnnotations(names []string) ([]TreeAnnotation, error) {
+	annotations := make([]TreeAnnotation, 0, len(names))
+	for _, name := range names {
+		switch annotation := TreeAnnotation(strings.ToLower(strings.TrimSpace(name))); annotation {
+		case TreeAnnotationSize, TreeAnnotationLines, TreeAnnotationTokens:
+			annotations = append(annotations, annotation)
+		default:
+			return nil, fmt.Errorf("%w: unknown annotation %q", ErrInvalidTreeOptions, name)
+		}
+	}
+
+	return annotations, nil
+}
+
+// treeMeasure is the size of a file, or the total size of the files of a directory
+type treeMeasure struct {
+	files  int
+	bytes  int
+	lines  int
+	tokens int
+}
+
+// treeEntry is a single entry of the rendered tree
+type treeEntry struct {
+	name       string
+	relPath    string
+	dir        bool
+	sizeStatus SizeStatus
+
+	// link is the target of a symbolic link
+	link string
+
+	// identicalTo is the path of the earlier file with the same content
+	identicalTo string
+
+	// excluded is set for the entries excluded from the collection
+	excluded *validators.Reason
+
+	// measure is set when there are annotations
+	measure *treeMeasure
+}
+
+// treeWriter writes the entries of the tree in one format, the entries come depth first with the children of a directory between descend and ascend
+type treeWriter interface {
+	// entry writes an entry, last tells if it is the last entry of its directory
+	entry(e *treeEntry, depth int, last bool)
+
+	// descend starts the children of the last written entry
+	descend()
+
+	// more tells how many entries of the current directory were left out, and if they are all files
+	more(count int, files bool, depth int)
+
+	// ascend ends the children of the current directory
+	ascend()
+
+	// end finishes the tree
+	end()
+}
+
+// treeRenderer walks the source tree and feeds the visible entries to a tree writer
+type treeRenderer struct {
+	options TreeOptions
+
+	// basePath the entry paths are relative to
+	basePath string
+
+	// output path which is left out of the tree
+	output string
+
+	// paths tells if the writer needs the relative paths of the entries
+	paths bool
+
+	// measures of the files and the directories, only set when there are annotations
+	measures map[*SourceTree]*treeMeasure
+
+	// excluded entries by the path of their directory, only set when they are shown
+	excluded map[string][]*SourceTree
+
+	// reasons of the excluded entries
+	reasons map[*SourceTree]*validators.Reason
+
+	// duplicates of the files, only set when they are deduplicated
+	duplicates *duplicateIndex
+
+	// current is the entry handed to the writer, reused so the walk does not allocate per entry
+	current treeEntry
+}
+
+// newTreeRenderer creates the renderer of the tree structure
+func newTreeRenderer(options TreeOptions, basePath string, output string) *treeRenderer {
+	return &treeRenderer{
+		options:  options,
+		basePath: basePath,
+		output:   output,
+	}
+}
+
+// addExcluded adds the entries excluded from the collection to the directories they were found in
+func (r *treeRenderer) addExcluded(skipped []SkippedPath) {
+	r.excluded = make(map[string][]*SourceTree)
+	r.reasons = make(map[*SourceTree]*validators.Reason)
+
+	for _, skippedPath := range skipped {
+		path := filepath.Join(r.basePath, skippedPath.Path)
+
+		node := &SourceTree{Root: &SourceNode{Name: extractName(path), Path: path}}
+		if isDirectory(path) {
+			node.Nodes = []*SourceTree{}
+		}
+
+		r.excluded[filepath.Dir(path)] = append(r.excluded[filepath.Dir(path)], node)
+		reason := skippedPath.Reason
+		r.reasons[node] = &reason
+	}
+}
+
+// measure sums up the stats of the files for every directory of the tree
+func (r *treeRenderer) measure(tree *SourceTree, files map[string]FileStats) treeMeasure {
+	if r.measures == nil {
+		r.measures = make(map[*SourceTree]*treeMeasure)
+	}
+
+	var m treeMeasure
+	if tree.Nodes == nil {
+		if stats, ok := files[tree.Root.Path]; ok {
+			m = treeMeasure{files: 1, bytes: stats.Bytes, lines: stats.Lines, tokens: stats.Tokens}
+		}
+	} else {
+		for _, node := range tree.Nodes {
+			if node == nil || node.Root.Path == r.output {
+				continue
+			}
+
+			child := r.measure(node, files)
+			m.files += child.files
+			m.bytes += child.bytes
+			m.lines += child.lines
+			m.tokens += child.tokens
+		}
+	}
+
+	r.measures[tree] = &m
+	return m
+}
+
+// render writes the tree with the writer, the root first and every entry below it
+func (r *treeRenderer) render(w treeWriter, tree *SourceTree) {
+	w.entry(r.entry(tree), 0, true)
+	r.renderChildren(w, tree, 1)
+	w.end()
+}
+
+// renderChildren writes the entries of a directory at the depth, up to the limits of the options
+func (r *treeRenderer) renderChildren(w treeWriter, tree *SourceTree, depth int) {
+	children := r.children(tree)
+	if len(children) == 0 || (r.options.MaxDepth > 0 && depth > r.options.MaxDepth) {
+		return
+	}
+
+	// Collapse the directories with too many entries
+	var hidden []*SourceTree
+	if r.options.MaxChildren > 0 && len(children) > r.options.MaxChildren {
+		children, hidden = children[:r.options.MaxChildren], children[r.options.MaxChildren:]
+	}
+
+	w.descend()
+	for i, node := range children {
+		w.entry(r.entry(node), depth, i == len(children)-1 && len(hidden) == 0)
+
+		if node.Nodes != nil {
+			r.renderChildren(w, node, depth+1)
+		}
+	}
+
+	if len(hidden) > 0 {
+		// The directories come first, so the hidden entries are mostly files
+		files := true
+		for _, node := range hidden {
+			files = files && node.Nodes == nil
+		}
+
+		w.more(len(hidden), files, depth)
+	}
+	w.ascend()
+}
+
+// entry describes a node of the source tree, the entry is only valid until the next call
+func (r *treeRenderer) entry(tree *SourceTree) *treeEntry {
+	e := &r.current
+	*e = treeEntry{
+		name:       tree.Root.Name,
+		dir:        tree.Nodes != nil,
+		sizeStatus: tree.Root.SizeStatus,
+		link:       tree.Root.LinkTarget,
+		excluded:   r.reasons[tree],
+	}
+
+	if !e.dir {
+		e.identicalTo = r.duplicates.originalOf(tree.Root.Path)
+	}
+
+	if r.paths {
+		relPath, _ := filepath.Rel(r.basePath, tree.Root.Path)
+		e.relPath = filepath.ToSlash(relPath)
+	}
+
+	if m := r.measures[tree]; m != nil && m.files > 0 {
+		e.measure = m
+	}
+
+	return e
+}
+
+// children returns the entries of a directory in the order of the options, directories first
+func (r *treeRenderer) children(tree *SourceTree) []*SourceTree {
+	children := make([]*SourceTree, 0, len(tree.Nodes))
+	for _, node := range tree.Nodes {
+		// Leave out the ignored entries and the output itself
+		if node == nil || node.Root.Path == r.output {
+			continue
+		}
+		children = append(children, node)
+	}
+
+	children = append(children, r.excluded[tree.Root.Path]...)
+
+	sort.SliceStable(children, func(i, j int) bool {
+		iDir, jDir := children[i].Nodes != nil, children[j].Nodes != nil
+		if iDir != jDir {
+			return iDir
+		}
+
+		if r.options.Order == TreeOrderNatural {
+			return naturalLess(children[i].Root.Name, children[j].Root.Name)
+		}
+
+		return children[i].Root.Name < children[j].Root.Name
+	})
+
+	return children
+}
+
+// newTreeWriter creates the writer of the tree format
+func newTreeWriter(format TreeFormat, out *bufio.Writer, options TreeOptions) treeWriter {
+	switch format {
+	case TreeFormatJSON:
+		return &jsonTreeWriter{out: out, options: options}
+	case TreeFormatMarkdown:
+		return &markdownTreeWriter{out: out, options: options}
+	default:
+		return newTextTreeWriter(out, options)
+	}
+}
+
+// annotations returns the annotations of the entry in the order of the options, directories start with their number of files
+func annotations(e *treeEntry, options TreeOptions) []string {
+	if e.measure == nil {
+		return nil
+	}
+
+	var annotations []string
+	if e.dir {
+		annotations = append(annotations, pluralize(e.measure.files, "file"))
+	}
+
+	for _, annotation := range options.Annotations {
+		switch annotation {
+		case TreeAnnotationSize:
+			annotations = append(annotations, formatSize(e.measure.bytes))
+		case TreeAnnotationLines:
+			annotations = append(annotations, pluralize(e.measure.lines, "line"))
+		case TreeAnnotationTokens:
+			annotations = append(annotations, "~"+pluralize(e.measure.tokens, "token"))
+		}
+	}
+
+	return annotations
+}
+
+// textTreeWriter draws the tree with connectors, one entry per line
+type textTreeWriter struct {
+	out     *bufio.Writer
+	options TreeOptions
+
+	// Connectors of the entries and the prefixes of their children
+	branch     string
+	lastBranch string
+	pipe       string
+	space      string
+
+	// prefix continues the lines of the parent directories, it grows and shrinks in place while the tree is walked
+	prefix []byte
+
+	// lengths of the prefix before every open directory
+	lengths []int
+
+	// last tells if the last written entry was the last one of its directory
+	last bool
+}
+
+func newTextTreeWriter(out *bufio.Writer, options TreeOptions) *textTreeWriter {
+	w := &textTreeWriter{
+		out:        out,
+		options:    options,
+		branch:     "├── ",
+		lastBranch: "└── ",
+		pipe:       "│   ",
+		space:      "    ",
+	}
+
+	if options.ASCII {
+		w.branch, w.lastBranch, w.pipe = "|-- ", "`-- ", "|   "
+	}
+
+	return w
+}
+
+func (w *textTreeWriter) entry(e *treeEntry, depth int, last bool) {
+	w.last = last
+
+	// The root has no connector
+	if depth > 0 {
+		w.writePrefix(last)
+	}
+
+	w.out.WriteString(e.name)
+
+	if e.link != "" {
+		w.out.WriteString(" -> " + e.link)
+	}
+
+	// Mark the files which are not complete in the output
+	if e.sizeStatus != "" {
+		w.out.WriteString(" [" + string(e.sizeStatus) + "]")
+	}
+
+	if e.excluded != nil {
+		w.out.WriteString(" [excluded: " + e.excluded.Rule + "]")
+	}
+
+	if e.identicalTo != "" {
+		w.out.WriteString(" [identical to " + e.identicalTo + "]")
+	}
+
+	if annotations := annotations(e, w.options); len(annotations) > 0 {
+		w.out.WriteString(" (" + strings.Join(annotations, ", ") + ")")
+	}
+
+	w.out.WriteByte('\n')
+}
+
+// writePrefix writes the prefixes of the parent directories and the connector of the entry
+func (w *textTreeWriter) writePrefix(last bool) {
+	w.out.Write(w.prefix)
+
+	if last {
+		w.out.WriteString(w.lastBranch)
+	} else {
+		w.out.WriteString(w.branch)
+	}
+}
+
+func (w *textTreeWriter) descend() {
+	w.lengths = append(w.lengths, len(w.prefix))
+
+	// The children of the root are not indented
+	if len(w.lengths) == 1 {
+		return
+	}
+
+	if w.last {
+		w.prefix = append(w.prefix, w.space...)
+	} else {
+		w.prefix = append(w.prefix, w.pipe...)
+	}
+}
+
+func (w *textTreeWriter) more(count int, files bool, depth int) {
+	w.writePrefix(true)
+	w.out.WriteString("... ")
+	w.out.WriteString(moreEntries(count, files))
+	w.out.WriteByte('\n')
+}
+
+func (w *textTreeWriter) ascend() {
+	w.prefix = w.prefix[:w.lengths[len(w.lengths)-1]]
+	w.lengths = w.lengths[:len(w.lengths)-1]
+}
+
+func (w *textTreeWriter) end() {}
+
+// markdownTreeWriter writes the tree as a nested list
+type markdownTreeWriter struct {
+	out     *bufio.Writer
+	options TreeOptions
+}
+
+func (w *markdownTreeWriter) entry(e *treeEntry, depth int, last bool) {
+	w.writeIndent(depth)
+	w.out.WriteString("- ")
+
+	name := "`" + e.name + "`"
+	if e.dir {
+		name = "**" + e.name + "/**"
+	}
+
+	if e.link != "" {
+		name += " → `" + e.link + "`"
+	}
+
+	// Strike through the excluded entries
+	if e.excluded != nil {
+		name = "~~" + name + "~~ _(excluded: " + e.excluded.Rule + ")_"
+	}
+	w.out.WriteString(name)
+
+	if e.sizeStatus != "" {
+		w.out.WriteString(" _(" + string(e.sizeStatus) + ")_")
+	}
+
+	if e.identicalTo != "" {
+		w.out.WriteString(" _(identical to `" + e.identicalTo + "`)_")
+	}
+
+	if annotations := annotations(e, w.options); len(annotations) > 0 {
+		w.out.WriteString(" — " + strings.Join(annotations, ", "))
+	}
+
+	w.out.WriteByte('\n')
+}
+
+// writeIndent indents the list item to its depth
+func (w *markdownTreeWriter) writeIndent(depth int) {
+	for range depth {
+		w.out.WriteString("  ")
+	}
+}
+
+func (w *markdownTreeWriter) descend() {}
+
+func (w *markdownTreeWriter) more(count int, files bool, depth int) {
+	w.writeIndent(depth)
+	w.out.WriteString("- _… " + moreEntries(count, files) + "_\n")
+}
+
+func (w *markdownTreeWriter) ascend() {}
+
+func (w *markdownTreeWriter) end() {}
+
+// jsonTreeEntry is an entry of the json tree format, the children are written separately
+type jsonTreeEntry struct {
+	Name        string             `json:"name"`
+	Path        string             `json:"path"`
+	Type        string             `json:"type"`
+	Link        string             `json:"link,omitempty"`
+	SizeStatus  SizeStatus         `json:"sizeStatus,omitempty"`
+	Excluded    *validators.Reason `json:"excluded,omitempty"`
+	IdenticalTo string             `json:"identicalTo,omitempty"`
+	Files       *int               `json:"files,omitempty"`
+	Bytes       *int               `json:"bytes,omitempty"`
+	Lines       *int               `json:"lines,omitempty"`
+	Tokens      *int               `json:"tokens,omitempty"`
+}
+
+// jsonTreeWriter writes the tree as nested json objects, the entries of a directory are in its children list
+type jsonTreeWriter struct {
+	out     *bufio.Writer
+	options TreeOptions
+
+	// open tells if the last written entry is still open for its children
+	open bool
+
+	// first tells for every open directory if no child was written yet
+	first []bool
+
+	// hidden is the number of left out entries of every open directory
+	hidden []int
+}
+
+func (w *jsonTreeWriter) entry(e *treeEntry, depth int, last bool) {
+	w.closeEntry()
+
+	if depth > 0 {
+		if !w.first[len(w.first)-1] {
+			w.out.WriteByte(',')
+		}
+		w.first[len(w.first)-1] = false
+	}
+
+	entry := jsonTreeEntry{
+		Name:        e.name,
+		Path:        e.relPath,
+		Type:        "file",
+		Link:        e.link,
+		SizeStatus:  e.sizeStatus,
+		Excluded:    e.excluded,
+		IdenticalTo: e.identicalTo,
+	}
+	if e.dir {
+		entry.Type = "dir"
+	}
+
+	if e.measure != nil {
+		if e.dir {
+			entry.Files = &e.measure.files
+		}
+
+		for _, annotation := range w.options.Annotations {
+			switch annotation {
+			case TreeAnnotationSize:
+				entry.Bytes = &e.measure.bytes
+			case TreeAnnotationLines:
+				entry.Lines = &e.measure.lines
+			case TreeAnnotationTokens:
+				entry.Tokens = &e.measure.tokens
+			}
+		}
+	}
+
+	data, _ := json.Marshal(entry)
+
+	// Leave the object open, so the children can be added to it
+	w.out.Write(data[:len(data)-1])
+	w.open = true
+}
+
+// closeEntry closes the last written entry if it got no children
+func (w *jsonTreeWriter) closeEntry() {
+	if w.open {
+		w.out.WriteByte('}')
+		w.open = false
+	}
+}
+
+func (w *jsonTreeWriter) descend() {
+	w.out.WriteString(`,"children":[`)
+	w.open = false
+	w.first = append(w.first, true)
+	w.hidden = append(w.hidden, 0)
+}
+
+func (w *jsonTreeWriter) more(count int, files bool, depth int) {
+	w.hidden[len(w.hidden)-1] = count
+}
+
+func (w *jsonTreeWriter) ascend() {
+	w.closeEntry()
+	w.out.WriteByte(']')
+
+	if hidden := w.hidden[len(w.hidden)-1]; hidden > 0 {
+		fmt.Fprintf(w.out, `,"more":%d`, hidden)
+	}
+
+	w.first = w.first[:len(w.first)-1]
+	w.hidden = w.hidden[:len(w.hidden)-1]
+	w.out.WriteByte('}')
+}
+
+func (w *jsonTreeWriter) end() {
+	w.closeEntry()
+	w.out.WriteByte('\n')
+}
+
+// moreEntries describes the number of entries left out of a directory
+func moreEntries(count int, files bool) string {
+	noun, plural := "entry", "entries"
+	if files {
+		noun, plural = "file", "files"
+	}
+
+	if count != 1 {
+		noun = plural
+	}
+
+	return fmt.Sprintf("%d more %s", count, noun)
+}
+
+// naturalLess compares the names ignoring case and with the runs of digits compared by their value, equal names fall back to a byte comparison
+func naturalLess(a string, b string) bool {
+	ra, rb := []rune(a), []rune(b)
+
+	i, j := 0, 0
+	for i < len(ra) && j < len(rb) {
+		if unicode.IsDigit(ra[i]) && unicode.IsDigit(rb[j]) {
+			// Compare the numbers without their leading zeros, first by length then digit by digit
+			si, sj := i, j
+			for i < len(ra) && unicode.IsDigit(ra[i]) {
+				i++
+			}
+			for j < len(rb) && unicode.IsDigit(rb[j]) {
+				j++
+			}
+
+			na := strings.TrimLeft(string(ra[si:i]), "0")
+			nb := strings.TrimLeft(string(rb[sj:j]), "0")
+			if len(na) != len(nb) {
+				return len(na) < len(nb)
+			}
+			if na != nb {
+				return na < nb
+			}
+			continue
+		}
+
+		ca, cb := unicode.ToLower(ra[i]), unicode.ToLower(rb[j])
+		if ca != cb {
+			return ca < cb
+		}
+		i++
+		j++
+	}
+
+	if len(ra)-i != len(rb)-j {
+		return len(ra)-i < len(rb)-j
+	}
+
+	return a < b
+}
+
+// formatSize formats the number of bytes in the largest fitting unit
+func formatSize(size int) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	value, suffix := float64(size)/unit, "KB"
+	for _, next := range []string{"MB", "GB"} {
+		if value < unit {
+			break
+		}
+		value, suffix = value/unit, next
+	}
+
+	return fmt.Sprintf("%.1f %s", value, suffix)
+}
+
+// pluralize formats the count with the noun, adding an s unless the count is one
+func pluralize(count int, noun string) string {
+	if count == 1 {
+		return "1 " + noun
+	}
+
+	return fmt.Sprintf("%d %ss", count, noun)
+}
+
+// statsByPath indexes the stats of the files by their absolute path
+func (sc *SourceCollector) statsByPath(stats *Stats) map[string]FileStats {
+	files := make(map[string]FileStats, len(stats.Files))
+	for _, file := range stats.Files {
+		files[filepath.Join(sc.BasePath, file.Path)] = file
+	}
+
+	return files
+}
@@ -0,0 +1,211 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hitesh22rana/sourcecollector/pkg/internal/testutil"
+	"github.com/hitesh22rana/sourcecollector/pkg/validators"
+)
+
+// syntheticTree builds a source tree without touching the disk, with files per directory and directories nested depth levels with fanout subdirectories each
+func syntheticTree(path string, depth int, fanout int, files int) *SourceTree {
+	tree := &SourceTree{
+		Root:  &SourceNode{Name: filepath.Base(path), Path: path},
+		Nodes: []*SourceTree{},
+	}
+
+	for i := 0; i < files; i++ {
+		name := fmt.Sprintf("file%d.go", i)
+		tree.Nodes = append(tree.Nodes, &SourceTree{
+			Root: &SourceNode{Name: name, Path: filepath.Join(path, name)},
+		})
+	}
+
+	if depth > 0 {
+		for i := 0; i < fanout; i++ {
+			tree.Nodes = append(tree.Nodes, syntheticTree(filepath.Join(path, fmt.Sprintf("dir%d", i)), depth-1, fanout, files))
+		}
+	}
+
+	return tree
+}
+
+// countFiles counts the files of the source tree
+func countFiles(tree *SourceTree) int {
+	if tree.Nodes == nil {
+		return 1
+	}
+
+	count := 0
+	for _, node := range tree.Nodes {
+		count += countFiles(node)
+	}
+
+	return count
+}
+
+func TestRenderSourceTree(t *testing.T) {
+	sc := &SourceCollector{BasePath: "/base", Tree: TreeOptions{Order: TreeOrderNatural}}
+	tree := syntheticTree("/base/input", 1, 2, 2)
+
+	got, err := sc.RenderSourceTree(context.Background(), tree, TreeFormatText)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `input
+├── dir0
+│   ├── file0.go
+│   └── file1.go
+├── dir1
+│   ├── file0.go
+│   └── file1.go
+├── file0.go
+└── file1.go
+`
+	if got != want {
+		t.Errorf("text tree:\n%s\nwant:\n%s", got, want)
+	}
+
+	sc.Tree.ASCII = true
+	sc.Tree.MaxChildren = 2
+	got, _ = sc.RenderSourceTree(context.Background(), tree, TreeFormatText)
+
+	want = "input\n|-- dir0\n|   |-- file0.go\n|   `-- file1.go\n|-- dir1\n|   |-- file0.go\n|   `-- file1.go\n`-- ... 2 more files\n"
+	if got != want {
+		t.Errorf("collapsed ascii tree:\n%s\nwant:\n%s", got, want)
+	}
+
+	sc.Tree.ASCII, sc.Tree.MaxChildren, sc.Tree.MaxDepth = false, 0, 1
+	got, _ = sc.RenderSourceTree(context.Background(), tree, TreeFormatJSON)
+
+	want = `{"name":"input","path":"input","type":"dir","children":[{"name":"dir0","path":"input/dir0","type":"dir"},{"name":"dir1","path":"input/dir1","type":"dir"},{"name":"file0.go","path":"input/file0.go","type":"file"},{"name":"file1.go","path":"input/file1.go","type":"file"}]}` + "\n"
+	if got != want {
+		t.Errorf("json tree:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// benchmarkWriteSourceTree renders the synthetic tree in the format
+func benchmarkWriteSourceTree(b *testing.B, tree *SourceTree, format TreeFormat) {
+	sc := &SourceCollector{BasePath: "/base", Tree: TreeOptions{Order: TreeOrderNatural}}
+
+	var sb strings.Builder
+	sc.WriteSourceTree(context.Background(), &sb, tree, format)
+	b.SetBytes(int64(sb.Len()))
+	b.ReportMetric(float64(countFiles(tree)), "files")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := sc.WriteSourceTree(context.Background(), io.Discard, tree, format); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkWriteSourceTree(b *testing.B) {
+	trees := []struct {
+		name string
+		tree *SourceTree
+	}{
+		// 1k, 10k and 100k files spread over a balanced hierarchy
+		{"balanced-1k", syntheticTree("/base/input", 2, 10, 9)},
+		{"balanced-10k", syntheticTree("/base/input", 3, 10, 9)},
+		{"balanced-100k", syntheticTree("/base/input", 4, 10, 9)},
+
+		// 100k files in a single directory
+		{"flat-100k", syntheticTree("/base/input", 0, 0, 100000)},
+
+		// A chain 1000 directories deep
+		{"deep-1k", syntheticTree("/base/input", 1000, 1, 1)},
+	}
+
+	for _, format := range []TreeFormat{TreeFormatText, TreeFormatJSON} {
+		for _, tree := range trees {
+			b.Run(string(format)+"/"+tree.name, func(b *testing.B) {
+				benchmarkWriteSourceTree(b, tree.tree, format)
+			})
+		}
+	}
+}
+
+// concurrencyValidator accepts every path and records how many goroutines check a path at the same time
+type concurrencyValidator struct {
+	active  *atomic.Int32
+	highest *atomic.Int32
+}
+
+func (v concurrencyValidator) IsIgnored(string) (bool, validators.Reason) {
+	active := v.active.Add(1)
+	defer v.active.Add(-1)
+
+	for highest := v.highest.Load(); active > highest && !v.highest.CompareAndSwap(highest, active); highest = v.highest.Load() {
+	}
+
+	// Give the other goroutines the time to overlap
+	time.Sleep(100 * time.Microsecond)
+	return false, validators.Reason{}
+}
+
+func TestGenerateSourceTreeConcurrency(t *testing.T) {
+	files := make(map[string]string)
+	for i := 0; i < 8; i++ {
+		for j := 0; j < 8; j++ {
+			files[fmt.Sprintf("pkg%d/sub%d/deep/file.go", i, j)] = "package deep\n"
+			files[fmt.Sprintf("pkg%d/sub%d/file%d.go", i, j, j)] = "package sub\n"
+		}
+	}
+	input := testutil.WriteFiles(t, files)
+
+	walk := func(maxConcurrency int) (string, int32) {
+		sc, err := NewSourceCollector(input, "", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sc.MaxConcurrency = maxConcurrency
+
+		validator := concurrencyValidator{active: &atomic.Int32{}, highest: &atomic.Int32{}}
+		sc.Validator = validator
+
+		ctx := context.Background()
+		sourceTree, err := sc.GenerateSourceTree(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sourceTreeStructure, err := sc.GenerateSourceTreeStructure(ctx, sourceTree)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The walker of the stream is bounded the same way
+		streamValidator := concurrencyValidator{active: &atomic.Int32{}, highest: &atomic.Int32{}}
+		sc.Validator = streamValidator
+		sc.walk(ctx, func(walkedPath) {})
+
+		return sourceTreeStructure + strings.Join(sc.SourceFilePaths(sourceTree), "\n"), max(validator.highest.Load(), streamValidator.highest.Load())
+	}
+
+	sequential, highest := walk(1)
+	if highest != 1 {
+		t.Errorf("sequential walk checked %d paths at the same time", highest)
+	}
+
+	// The walk gives the same tree whatever the concurrency, without more goroutines than allowed
+	for _, maxConcurrency := range []int{2, 4, 16} {
+		parallel, highest := walk(maxConcurrency)
+		if parallel != sequential {
+			t.Errorf("walk with %d goroutines:\n%s\nwant\n%s", maxConcurrency, parallel, sequential)
+		}
+		if highest > int32(maxConcurrency) {
+			t.Errorf("walk with %d goroutines checked %d paths at the same time", maxConcurrency, highest)
+		}
+	}
+}
@@ -1,6 +1,10 @@
 package pkg
 
 import (
+	"sync"
+
+	"github.com/hitesh22rana/sourcecollector/pkg/cache"
+	"github.com/hitesh22rana/sourcecollector/pkg/secrets"
 	"github.com/hitesh22rana/sourcecollector/pkg/validators"
 )
 
@@ -20,8 +24,77 @@ type SourceCollector struct {
 
 	// Max Concurrency to be used for io operations
 	MaxConcurrency int
+
+	// Redactor of the source code, if set secrets are redacted before writing to the output
+	Redactor *secrets.Redactor
+
+	// MaxLineLength is the maximum number of characters kept per line, longer lines are truncated with a marker, 0 means unlimited
+	MaxLineLength int
+
+	// SizeLimit is the maximum size of a single file, nil means unlimited
+	SizeLimit *SizeLimit
+
+	// LineNumbers prefixes every line of the source code with its line number in the original file
+	LineNumbers bool
+
+	// OnError decides if the collection continues or aborts when a file cannot be collected
+	OnError ErrorPolicy
+
+	// WriteMode decides if an existing output file is overwritten, kept or appended to
+	WriteMode WriteMode
+
+	// Format of the output
+	Format Format
+
+	// Tree configures how the tree structure is rendered
+	Tree TreeOptions
+
+	// Cache of the transformed files, if set unchanged files are not read again
+	Cache *cache.Cache
+
+	// Outline replaces the content of the supported files with their declarations and signatures, without the bodies
+	Outline bool
+
+	// Dedupe writes the files with the same content as an earlier file as a reference to it
+	Dedupe bool
+
+	// Symlinks decides if the symbolic links are skipped or followed, cycles are never followed.
+	// NewSourceCollector only follows the links pointing inside the input, set SymlinkFollow to also follow the ones pointing outside of it.
+	Symlinks SymlinkPolicy
+
+	// MaxTokens is the estimated token budget of the file contents written, 0 means unlimited.
+	// A file which does not fit is left out and the later files are still written if they fit, see OmittedPaths.
+	MaxTokens int
+
+	skippedMu sync.Mutex
+	skipped   []SkippedPath
+
+	// omitted files of the last collection, over the token budget
+	omitted []string
+
+	// duplicates of the last walk, only set when Dedupe is
+	duplicates *duplicateIndex
+}
+
+// SkippedPath is a path excluded from the collection and the reason why
+type SkippedPath struct {
+	// Path relative to the base path
+	Path string `json:"path"`
+
+	validators.Reason
 }
 
+// ErrorPolicy decides what happens when a single file cannot be collected
+type ErrorPolicy string
+
+const (
+	// ErrorPolicySkip leaves the file out and continues with the rest
+	ErrorPolicySkip ErrorPolicy = "skip"
+
+	// ErrorPolicyFail aborts the collection
+	ErrorPolicyFail ErrorPolicy = "fail"
+)
+
 // SourceTree is a struct that holds the source code tree structure
 type SourceTree struct {
 	// Root of the source code tree
@@ -38,4 +111,10 @@ type SourceNode struct {
 
 	// Path of the source code node
 	Path string
+
+	// SizeStatus of the source code node, set if the file was truncated or skipped because of the size limit
+	SizeStatus SizeStatus
+
+	// LinkTarget of the source code node, set if the node is a symbolic link which was followed
+	LinkTarget string
 }
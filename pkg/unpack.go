@@ -0,0 +1,212 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ConflictPolicy decides what happens when an unpacked file already exists with a different content
+type ConflictPolicy string
+
+const (
+	// ConflictFail stops before anything is written if any file would be changed
+	ConflictFail ConflictPolicy = "fail"
+
+	// ConflictSkip keeps the existing files and only creates the new ones
+	ConflictSkip ConflictPolicy = "skip"
+
+	// ConflictOverwrite replaces the existing files
+	ConflictOverwrite ConflictPolicy = "overwrite"
+)
+
+// UnpackAction is what happens to a single file of the bundle
+type UnpackAction string
+
+const (
+	UnpackCreate    UnpackAction = "create"
+	UnpackOverwrite UnpackAction = "overwrite"
+	UnpackUnchanged UnpackAction = "unchanged"
+	UnpackSkip      UnpackAction = "skip"
+
+	// UnpackConflict is an existing file which makes the unpack fail, only planned in a dry run
+	UnpackConflict UnpackAction = "conflict"
+)
+
+// UnpackOptions configure where and how a bundle is unpacked
+type UnpackOptions struct {
+	// Target directory the paths of the bundle are relative to
+	Target string
+
+	// StripComponents removes this many leading directories from every path, e.g. 1 to drop the name of the input directory
+	StripComponents int
+
+	// OnConflict decides what happens to the existing files with a different content
+	OnConflict ConflictPolicy
+
+	// DryRun only plans the changes and computes their diffs, without writing anything
+	DryRun bool
+}
+
+// UnpackResult is the outcome of a single file of the bundle
+type UnpackResult struct {
+	// Path of the file in the bundle
+	Path string
+
+	// Target path the file is written to
+	Target string
+
+	// Action taken, or planned in a dry run
+	Action UnpackAction
+
+	// Diff from the existing file to the file of the bundle, set in a dry run
+	Diff string
+}
+
+// Unpack recreates the files of a bundle under the target directory.
+// Absolute paths and paths leaving the target directory, also through a symbolic link, are rejected before anything is written.
+// So are the files whose content was transformed for the output, e.g. numbered or redacted, as they would replace the originals with the transformed content.
+func Unpack(files []BundleFile, opts UnpackOptions) ([]UnpackResult, error) {
+	target, err := filepath.Abs(opts.Target)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnpack, err)
+	}
+
+	// Plan every file first, so an unsafe path or a conflict leaves the target untouched
+	results := make([]UnpackResult, 0, len(files))
+	seen := make(map[string]struct{})
+	for _, file := range files {
+		if len(file.Transformed) > 0 {
+			return nil, fmt.Errorf("%w: %s was changed by %s", ErrTransformedFile, file.Path, strings.Join(file.Transformed, ", "))
+		}
+
+		path, err := safeTargetPath(target, file.Path, opts.StripComponents)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := seen[path]; ok {
+			return nil, fmt.Errorf("%w: %s appears more than once", ErrInvalidBundle, file.Path)
+		}
+		seen[path] = struct{}{}
+
+		result := UnpackResult{Path: file.Path, Target: path, Action: UnpackCreate}
+
+		existing, err := os.ReadFile(path)
+		switch {
+		case err == nil && string(existing) == file.Content:
+			result.Action = UnpackUnchanged
+		case err == nil && opts.OnConflict == ConflictSkip:
+			result.Action = UnpackSkip
+		case err == nil && opts.OnConflict == ConflictOverwrite:
+			result.Action = UnpackOverwrite
+		case err == nil && opts.DryRun:
+			result.Action = UnpackConflict
+		case err == nil:
+			return nil, fmt.Errorf("%w: %s", ErrFileExists, path)
+		case !errors.Is(err, fs.ErrNotExist):
+			return nil, fmt.Errorf("%w: %v", ErrUnpack, err)
+		}
+
+		if opts.DryRun && result.Action != UnpackUnchanged && result.Action != UnpackSkip {
+			oldName := "/dev/null"
+			if result.Action != UnpackCreate {
+				oldName = "a/" + filepath.ToSlash(file.Path)
+			}
+			result.Diff = unifiedDiff(oldName, "b/"+filepath.ToSlash(file.Path), string(existing), file.Content)
+		}
+
+		results = append(results, result)
+	}
+
+	if opts.DryRun {
+		return results, nil
+	}
+
+	for i, file := range files {
+		if results[i].Action != UnpackCreate && results[i].Action != UnpackOverwrite {
+			continue
+		}
+
+		if err := writeUnpackedFile(results[i].Target, file.Content, results[i].Action); err != nil {
+			return results[:i], err
+		}
+	}
+
+	return results, nil
+}
+
+// safeTargetPath resolves the path of a bundle file under the target directory, rejecting the paths which would end up outside of it
+func safeTargetPath(target string, path string, stripComponents int) (string, error) {
+	slashPath := filepath.ToSlash(path)
+	if path == "" || filepath.IsAbs(path) || strings.HasPrefix(slashPath, "/") || filepath.VolumeName(path) != "" {
+		return "", fmt.Errorf("%w: %q is absolute", ErrUnsafePath, path)
+	}
+
+	parts := strings.Split(slashPath, "/")
+	for _, part := range parts {
+		if part == ".." {
+			return "", fmt.Errorf("%w: %q leaves the target directory", ErrUnsafePath, path)
+		}
+	}
+
+	if stripComponents >= len(parts) {
+		return "", fmt.Errorf("%w: %q has fewer than %d leading directories", ErrUnsafePath, path, stripComponents)
+	}
+
+	resolved := filepath.Join(append([]string{target}, parts[stripComponents:]...)...)
+	if resolved == target {
+		return "", fmt.Errorf("%w: %q is not a file", ErrUnsafePath, path)
+	}
+
+	// An existing symbolic link on the way could point anywhere
+	relPath, _ := filepath.Rel(target, resolved)
+	current := target
+	for _, part := range strings.Split(relPath, string(filepath.Separator)) {
+		current = filepath.Join(current, part)
+
+		info, err := os.Lstat(current)
+		if err != nil {
+			break
+		}
+
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return "", fmt.Errorf("%w: %q goes through the symbolic link %s", ErrUnsafePath, path, current)
+		}
+	}
+
+	return resolved, nil
+}
+
+// writeUnpackedFile atomically writes the content to the path, creating its directories
+func writeUnpackedFile(path string, content string, action UnpackAction) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("%w: %v", ErrUnpack, err)
+	}
+
+	// A created file must not clobber a file which appeared since it was planned
+	mode := WriteModeOverwrite
+	if action == UnpackCreate {
+		mode = WriteModeNoClobber
+	}
+
+	file, err := createOutputFile(path, mode)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnpack, err)
+	}
+	defer file.discard()
+
+	if _, err := io.WriteString(file, content); err != nil {
+		return fmt.Errorf("%w: %v", ErrUnpack, err)
+	}
+
+	if err := file.commit(); err != nil {
+		return fmt.Errorf("%w: %v", ErrUnpack, err)
+	}
+
+	return nil
+}
@@ -1,10 +1,18 @@
 package pkg
 
 import (
+	"bytes"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+
+	"github.com/hitesh22rana/sourcecollector/pkg/validators"
 )
 
+// bytesPerToken is the average number of bytes per LLM token used for estimations
+const bytesPerToken = 4
+
 // isValidPath checks if the path is valid or not
 func isValidPath(path string) bool {
 	_, err := os.Stat(path)
@@ -25,3 +33,58 @@ func isDirectory(path string) bool {
 func extractName(path string) string {
 	return filepath.Base(path)
 }
+
+// numberLines prefixes every line of the content with its line number, padded to the width of the last line number
+func numberLines(content []byte) []byte {
+	lines := splitLines(content)
+	width := len(strconv.Itoa(len(lines)))
+
+	var numbered bytes.Buffer
+	numbered.Grow(len(content) + len(lines)*(width+3))
+	for i, line := range lines {
+		fmt.Fprintf(&numbered, "%*d | ", width, i+1)
+		numbered.Write(line)
+	}
+
+	return numbered.Bytes()
+}
+
+// countLines counts the lines of the content, the last line counts without a final newline
+func countLines(content []byte) int {
+	lines := bytes.Count(content, []byte("\n"))
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		lines++
+	}
+
+	return lines
+}
+
+// splitLines splits the content into lines, keeping the line endings
+func splitLines(content []byte) [][]byte {
+	lines := bytes.SplitAfter(content, []byte("\n"))
+
+	// Drop the empty element after the trailing newline
+	if len(lines) > 0 && len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
+
+// estimateTokens estimates the number of LLM tokens of the content, using the common heuristic of about 4 bytes per token
+func estimateTokens(content []byte) int {
+	return (len(content) + bytesPerToken - 1) / bytesPerToken
+}
+
+// newValidator makes a gitignore based validator for the input directory, or the default validator if it has no .gitignore
+func newValidator(input string) validators.Validator {
+	// Make a new gitignore based validator
+	validator, err := validators.NewGitIgnoreBasedValidator(input)
+
+	// If the gitIgnoreBasedValidator is nil, then make a new default validator
+	if err != nil {
+		return validators.NewDefaultValidator()
+	}
+
+	return validator
+}
@@ -0,0 +1,72 @@
+package pkg
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNumberLines(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"single line", "package main\n", "1 | package main\n"},
+		{"blank lines", "a\n\nb\n", "1 | a\n2 | \n3 | b\n"},
+		{"no final newline", "a\nb", "1 | a\n2 | b"},
+		{"crlf", "a\r\nb\r\n", "1 | a\r\n2 | b\r\n"},
+		{"padded", strings.Repeat("x\n", 10), " 1 | x\n 2 | x\n 3 | x\n 4 | x\n 5 | x\n 6 | x\n 7 | x\n 8 | x\n 9 | x\n10 | x\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := string(numberLines([]byte(test.content))); got != test.want {
+				t.Errorf("numberLines(%q) = %q, want %q", test.content, got, test.want)
+			}
+		})
+	}
+}
+
+func TestCountLines(t *testing.T) {
+	tests := map[string]int{
+		"":       0,
+		"\n":     1,
+		"a":      1,
+		"a\nb\n": 2,
+		"a\nb":   2,
+		"a\r\nb": 2,
+	}
+
+	for content, want := range tests {
+		if got := countLines([]byte(content)); got != want {
+			t.Errorf("countLines(%q) = %d, want %d", content, got, want)
+		}
+	}
+}
+
+func TestLineNumbersFormats(t *testing.T) {
+	files := map[string]string{"main.go": "package main\n\nfunc main() {}\n"}
+
+	// The numbered content is the same in every format
+	var contents []string
+	for _, format := range []Format{FormatText, FormatJSON} {
+		bundle, err := ParseBundle(collect(t, files, format, func(sc *SourceCollector) {
+			sc.LineNumbers = true
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(bundle) != 1 {
+			t.Fatalf("%s: parsed %d files, want 1", format, len(bundle))
+		}
+
+		contents = append(contents, bundle[0].Content)
+	}
+
+	want := "1 | package main\n2 | \n3 | func main() {}\n"
+	for i, content := range contents {
+		if content != want {
+			t.Errorf("content %d %q, want %q", i, content, want)
+		}
+	}
+}
@@ -8,18 +8,18 @@ func NewDefaultValidator() *DefaultValidator {
 	return &DefaultValidator{}
 }
 
-// IsIgnored checks if the file is ignored or not
-func (v *DefaultValidator) IsIgnored(path string) bool {
+// IsIgnored checks if the file is ignored or not, and if so returns the reason why
+func (v *DefaultValidator) IsIgnored(path string) (bool, Reason) {
+	return v.IsIgnoredEntry(path, isDirectory(path))
+}
+
+// IsIgnoredEntry checks if the file of the given type is ignored or not, and if so returns the reason why
+func (v *DefaultValidator) IsIgnoredEntry(path string, isDir bool) (bool, Reason) {
 	// Check if the file is a sensitive file or a markdown file
 	if isSensitiveFile(path) {
-		return true
-	}
-
-	// Check if the file is not a directory and is not a programming file
-	if !isDirectory(path) && !isProgrammingFile(path) && !isInformativeFile(path) {
-		return true
+		return true, sensitiveFileReason
 	}
 
-	// Lastly, check if the file is ignored by default
-	return isUnwantedFilesAndFolders(path)
+	// Check if the file is not a programming file, or is ignored by default
+	return isIgnoredByDefault(path, isDir)
 }
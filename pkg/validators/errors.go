@@ -0,0 +1,7 @@
+package validators
+
+import "errors"
+
+var (
+	ErrInvalidPattern = errors.New("invalid glob pattern")
+)
@@ -1,6 +1,7 @@
 package validators
 
 import (
+	"fmt"
 	"path/filepath"
 
 	ignore "github.com/sabhiram/go-gitignore"
@@ -10,37 +11,46 @@ import (
 type GitIgnoreBasedValidator struct {
 	// GitIgnore is used to check if the file is ignored by .gitignore
 	GitIgnore *ignore.GitIgnore
+
+	// Path of the .gitignore file
+	Path string
 }
 
 // NewGitIgnoreBasedValidator creates a new GitIgnoreBasedValidator
 func NewGitIgnoreBasedValidator(path string) (*GitIgnoreBasedValidator, error) {
-	gitIgnore, err := ignore.CompileIgnoreFile(filepath.Join(path, ".gitignore"))
+	gitIgnorePath := filepath.Join(path, ".gitignore")
+	gitIgnore, err := ignore.CompileIgnoreFile(gitIgnorePath)
 	if err != nil {
 		return nil, err
 	}
 
 	return &GitIgnoreBasedValidator{
 		GitIgnore: gitIgnore,
+		Path:      gitIgnorePath,
 	}, nil
 }
 
-// IsIgnored checks if the file is ignored by .gitignore
-func (v *GitIgnoreBasedValidator) IsIgnored(path string) bool {
+// IsIgnored checks if the file is ignored by .gitignore, and if so returns the reason why
+func (v *GitIgnoreBasedValidator) IsIgnored(path string) (bool, Reason) {
+	return v.IsIgnoredEntry(path, isDirectory(path))
+}
+
+// IsIgnoredEntry checks if the file of the given type is ignored by .gitignore, and if so returns the reason why
+func (v *GitIgnoreBasedValidator) IsIgnoredEntry(path string, isDir bool) (bool, Reason) {
 	// Check if the file is a sensitive file
 	if isSensitiveFile(path) {
-		return true
+		return true, sensitiveFileReason
 	}
 
 	// Check if the file name or extension is ignored by .gitignore
-	if v.GitIgnore.MatchesPath(path) {
-		return true
-	}
-
-	// Check if the file is not a directory and is not a programming file or informative file
-	if !isDirectory(path) && !isProgrammingFile(path) && !isInformativeFile(path) {
-		return true
+	if matches, pattern := v.GitIgnore.MatchesPathHow(path); matches {
+		return true, Reason{
+			Rule:   RuleGitIgnore,
+			Source: fmt.Sprintf("%s:%d", v.Path, pattern.LineNo),
+			Detail: fmt.Sprintf("matches pattern %q", pattern.Line),
+		}
 	}
 
-	// Lastly, check if the file is ignored by default
-	return isUnwantedFilesAndFolders(path)
+	// Check if the file is not a programming file or informative file, or is ignored by default
+	return isIgnoredByDefault(path, isDir)
 }
@@ -1,6 +1,7 @@
 package validators
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -499,9 +500,76 @@ var (
 	}
 )
 
+// Rules by which a path can be ignored
+const (
+	RuleSensitiveFile        = "sensitive-file"
+	RuleGitIgnore            = "gitignore"
+	RuleUnsupportedExtension = "unsupported-extension"
+	RuleUnwantedPath         = "unwanted-path"
+	RuleExcludePattern       = "exclude-pattern"
+	RuleIncludePattern       = "include-pattern"
+	RuleSymlink              = "symlink"
+)
+
 // Validator is an interface that defines the methods to validate the files
 type Validator interface {
-	IsIgnored(path string) bool
+	// IsIgnored checks if the path is ignored, and if so returns the reason why
+	IsIgnored(path string) (bool, Reason)
+}
+
+// EntryValidator is a Validator which can reuse the type of the path known from the directory listing, so the path is not stat'ed again
+type EntryValidator interface {
+	Validator
+
+	// IsIgnoredEntry checks if the path of the given type is ignored, and if so returns the reason why
+	IsIgnoredEntry(path string, isDir bool) (bool, Reason)
+}
+
+// IsIgnoredEntry checks if the path of the given type is ignored by the validator, without a stat if the validator supports it
+func IsIgnoredEntry(v Validator, path string, isDir bool) (bool, Reason) {
+	if ev, ok := v.(EntryValidator); ok {
+		return ev.IsIgnoredEntry(path, isDir)
+	}
+
+	return v.IsIgnored(path)
+}
+
+// Reason explains why a path is ignored
+type Reason struct {
+	// Rule which ignored the path
+	Rule string `json:"rule"`
+
+	// Source of the rule, e.g. the .gitignore file and line
+	Source string `json:"source"`
+
+	// Detail of the match
+	Detail string `json:"detail"`
+}
+
+func (r Reason) String() string {
+	return r.Rule + " (" + r.Source + "): " + r.Detail
+}
+
+// isIgnoredByDefault checks the rules shared by all the validators, other than the sensitive file check
+func isIgnoredByDefault(path string, isDir bool) (bool, Reason) {
+	// Check if the file is not a directory and is not a programming file or informative file
+	if !isDir && !isProgrammingFile(path) && !isInformativeFile(path) {
+		return true, Reason{
+			Rule:   RuleUnsupportedExtension,
+			Source: "validProgrammingFileExtensions",
+			Detail: fmt.Sprintf("extension %q is not a programming or informative file", filepath.Ext(path)),
+		}
+	}
+
+	// Lastly, check if the file is ignored by default
+	return isUnwantedFilesAndFolders(path, isDir)
+}
+
+// sensitiveFileReason is the reason of a path ignored by isSensitiveFile
+var sensitiveFileReason = Reason{
+	Rule:   RuleSensitiveFile,
+	Source: "isSensitiveFile",
+	Detail: "name starts with a dot",
 }
 
 // Check if the path is directory or not
@@ -522,27 +590,25 @@ func isSensitiveFile(path string) bool {
 	return name[0] == '.'
 }
 
-// isUnwantedFilesAndFolders checks if the file or directory is unwanted or not
-func isUnwantedFilesAndFolders(path string) bool {
-	// Check if the file or directory or not
-	fileInfo, err := os.Stat(path)
-	if err != nil {
-		return false
-	}
-
+// isUnwantedFilesAndFolders checks if the file or directory is unwanted or not, and if so returns the reason why
+func isUnwantedFilesAndFolders(path string, isDir bool) (bool, Reason) {
 	// Check if the file or directory is unwanted
-	if fileInfo.IsDir() && isSensitiveFile(path) {
-		return true
+	if isDir && isSensitiveFile(path) {
+		return true, sensitiveFileReason
 	}
 
 	// Check if the file or directory is unwanted
 	for _, unwantedFileAndFoler := range unwantedFilesAndFolders {
 		if strings.Contains(path, unwantedFileAndFoler) {
-			return true
+			return true, Reason{
+				Rule:   RuleUnwantedPath,
+				Source: "unwantedFilesAndFolders",
+				Detail: fmt.Sprintf("path contains %q", unwantedFileAndFoler),
+			}
 		}
 	}
 
-	return false
+	return false, Reason{}
 }
 
 // isInformativeFile checks if the file is an informative file or not
@@ -0,0 +1,124 @@
+package validators
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// PatternValidator narrows down the paths of another validator with include and exclude glob patterns
+type PatternValidator struct {
+	// Validator which is checked first
+	Validator Validator
+
+	// Root the patterns are relative to
+	Root string
+
+	// Include patterns, if any is set only the files matching one of them are kept
+	Include []string
+
+	// Exclude patterns, the paths matching one of them are ignored
+	Exclude []string
+}
+
+// NewPatternValidator creates a new PatternValidator, the patterns are checked to be valid globs
+func NewPatternValidator(validator Validator, root string, include []string, exclude []string) (*PatternValidator, error) {
+	for _, pattern := range append(append([]string{}, include...), exclude...) {
+		if _, err := path.Match(strings.ReplaceAll(pattern, "**", "*"), ""); err != nil {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidPattern, pattern)
+		}
+	}
+
+	return &PatternValidator{
+		Validator: validator,
+		Root:      root,
+		Include:   include,
+		Exclude:   exclude,
+	}, nil
+}
+
+// IsIgnored checks if the path is ignored by the validator or the patterns, and if so returns the reason why
+func (v *PatternValidator) IsIgnored(p string) (bool, Reason) {
+	return v.IsIgnoredEntry(p, isDirectory(p))
+}
+
+// IsIgnoredEntry checks if the path of the given type is ignored by the validator or the patterns, and if so returns the reason why
+func (v *PatternValidator) IsIgnoredEntry(p string, isDir bool) (bool, Reason) {
+	if ignored, reason := IsIgnoredEntry(v.Validator, p, isDir); ignored {
+		return true, reason
+	}
+
+	relPath, err := filepath.Rel(v.Root, p)
+	if err != nil || relPath == "." {
+		return false, Reason{}
+	}
+	relPath = filepath.ToSlash(relPath)
+
+	// Check if the path is excluded, a directory excludes everything under it
+	for _, pattern := range v.Exclude {
+		if matchPattern(pattern, relPath) {
+			return true, Reason{
+				Rule:   RuleExcludePattern,
+				Source: "--exclude",
+				Detail: fmt.Sprintf("matches pattern %q", pattern),
+			}
+		}
+	}
+
+	// The include patterns only apply to files, so the walk can still reach the files inside the directories
+	if len(v.Include) == 0 || isDir {
+		return false, Reason{}
+	}
+
+	for _, pattern := range v.Include {
+		if matchPattern(pattern, relPath) {
+			return false, Reason{}
+		}
+	}
+
+	return true, Reason{
+		Rule:   RuleIncludePattern,
+		Source: "--include",
+		Detail: "matches none of the include patterns",
+	}
+}
+
+// matchPattern checks if the slash separated relative path matches the glob pattern.
+// A pattern without a slash matches the name at any depth, and a ** segment matches any number of directories.
+func matchPattern(pattern string, relPath string) bool {
+	pattern = strings.Trim(filepath.ToSlash(pattern), "/")
+	if !strings.Contains(pattern, "/") {
+		matched, _ := path.Match(pattern, path.Base(relPath))
+		return matched
+	}
+
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(relPath, "/"))
+}
+
+// matchSegments matches the path segments against the pattern segments
+func matchSegments(pattern []string, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// Try to let the ** segment swallow zero or more path segments
+			for i := 0; i <= len(segments); i++ {
+				if matchSegments(pattern[1:], segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(segments) == 0 {
+			return false
+		}
+
+		if matched, _ := path.Match(pattern[0], segments[0]); !matched {
+			return false
+		}
+
+		pattern, segments = pattern[1:], segments[1:]
+	}
+
+	return len(segments) == 0
+}
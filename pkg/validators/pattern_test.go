@@ -0,0 +1,71 @@
+package validators
+
+import "testing"
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "cmd/cli/root.go", true},
+		{"*.go", "main.py", false},
+		{"cmd/*.go", "cmd/main.go", true},
+		{"cmd/*.go", "cmd/cli/root.go", false},
+		{"cmd/**/*.go", "cmd/main.go", true},
+		{"cmd/**/*.go", "cmd/cli/root.go", true},
+		{"**/testdata", "pkg/a/testdata", true},
+		{"**/testdata", "testdata", true},
+		{"docs/**", "docs", true},
+		{"docs/**", "docs/a/b.md", true},
+		{"docs/**", "pkg/docs/a.md", false},
+		{"/pkg/", "pkg", true},
+	}
+
+	for _, test := range tests {
+		if got := matchPattern(test.pattern, test.path); got != test.want {
+			t.Errorf("matchPattern(%q, %q) = %v, want %v", test.pattern, test.path, got, test.want)
+		}
+	}
+}
+
+// keepAll is a validator which keeps every path
+type keepAll struct{}
+
+func (keepAll) IsIgnored(string) (bool, Reason) {
+	return false, Reason{}
+}
+
+func TestPatternValidator(t *testing.T) {
+	root := t.TempDir()
+
+	v, err := NewPatternValidator(keepAll{}, root, []string{"**/*.go"}, []string{"internal/**"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		rule string
+	}{
+		{root + "/main.go", ""},
+		{root + "/cmd/main.go", ""},
+		{root + "/README.md", RuleIncludePattern},
+		{root + "/internal", RuleExcludePattern},
+		{root + "/internal/a.go", RuleExcludePattern},
+	}
+
+	for _, test := range tests {
+		ignored, reason := v.IsIgnored(test.path)
+		if ignored != (test.rule != "") || reason.Rule != test.rule {
+			t.Errorf("IsIgnored(%q) = %v, %q, want rule %q", test.path, ignored, reason.Rule, test.rule)
+		}
+	}
+}
+
+func TestNewPatternValidatorRejectsInvalidPatterns(t *testing.T) {
+	if _, err := NewPatternValidator(keepAll{}, "/", []string{"[a-"}, nil); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+}
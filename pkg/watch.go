@@ -0,0 +1,261 @@
+package pkg
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/hitesh22rana/sourcecollector/pkg/cache"
+)
+
+// WatchSummary describes a single rebuild of the output in watch mode
+type WatchSummary struct {
+	// Added files to the output since the previous build
+	Added []string
+
+	// Modified files in the output since the previous build
+	Modified []string
+
+	// Removed files from the output since the previous build
+	Removed []string
+
+	// Duration of the build
+	Duration time.Duration
+
+	// Failed are the files which could not be collected, the output was still written without them
+	Failed *CollectionError
+
+	// Err of the build if the output was not written, the watch goes on after a failed build
+	Err error
+}
+
+// fileVersion identifies the content of a collected file as it is written to the output
+type fileVersion [sha256.Size]byte
+
+// Watch builds the output and rebuilds it whenever a collected file changes, until the context is done.
+// Events for paths ignored by the validator are dropped, and bursts of events within the debounce interval lead to a single rebuild.
+// If no cache is set, a temporary one is used so the rebuilds only re-read the changed files.
+func (sc *SourceCollector) Watch(ctx context.Context, debounce time.Duration, onBuild func(summary *WatchSummary)) error {
+	if sc.Cache == nil {
+		cacheDir, err := os.MkdirTemp("", "sourcecollector-cache-*")
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrWatch, err)
+		}
+		defer os.RemoveAll(cacheDir)
+
+		sc.Cache, err = cache.Open(cacheDir)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrWatch, err)
+		}
+		defer func() { sc.Cache = nil }()
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrWatch, err)
+	}
+	defer watcher.Close()
+
+	watched := make(map[string]struct{})
+	var versions map[string]fileVersion
+
+	rebuild := func() {
+		startTime := time.Now()
+
+		sourceTree, err := sc.build(ctx)
+
+		summary := sc.newWatchSummary(err)
+
+		if sourceTree != nil {
+			sc.watchDirectories(watcher, sourceTree, watched)
+		}
+
+		// Compare with the previous output only if this one was written
+		if sourceTree != nil && summary.Err == nil {
+			current := sc.fileVersions(ctx, sourceTree)
+			if versions != nil {
+				summary.Added, summary.Modified, summary.Removed = diffVersions(versions, current)
+			}
+			versions = current
+		}
+
+		// A cancelled build is not worth reporting, the watch is over
+		if ctx.Err() != nil {
+			return
+		}
+
+		summary.Duration = time.Since(startTime)
+		onBuild(summary)
+	}
+
+	rebuild()
+
+	// The timer fires once the events have settled for the debounce interval
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+
+			if !sc.isRelevantEvent(event) {
+				continue
+			}
+
+			timer.Reset(debounce)
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+
+			onBuild(&WatchSummary{Err: fmt.Errorf("%w: %v", ErrWatch, err)})
+
+		case <-timer.C:
+			rebuild()
+		}
+	}
+}
+
+// newWatchSummary makes the summary of a build which returned err, the files which could not be collected are only an error if they kept the output from being written
+func (sc *SourceCollector) newWatchSummary(err error) *WatchSummary {
+	summary := &WatchSummary{Err: err}
+	if errors.As(err, &summary.Failed) && sc.OnError != ErrorPolicyFail {
+		summary.Err = nil
+	} else {
+		summary.Failed = nil
+	}
+
+	return summary
+}
+
+// build generates the source tree and saves the output
+func (sc *SourceCollector) build(ctx context.Context) (*SourceTree, error) {
+	sourceTree, err := sc.GenerateSourceTree(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sourceTreeStructure, err := sc.GenerateSourceTreeStructure(ctx, sourceTree)
+	if err != nil {
+		return sourceTree, err
+	}
+
+	return sourceTree, sc.SaveSourceCode(ctx, sourceTree, sourceTreeStructure)
+}
+
+// isRelevantEvent checks if the event may change the output
+func (sc *SourceCollector) isRelevantEvent(event fsnotify.Event) bool {
+	// Ignore the output itself, its temporary file and pure permission changes
+	if event.Name == sc.Output || strings.HasPrefix(filepath.Base(event.Name), "."+filepath.Base(sc.Output)) || event.Op == fsnotify.Chmod {
+		return false
+	}
+
+	// Removed paths cannot be validated anymore, but they may have been collected
+	if event.Op.Has(fsnotify.Remove) || event.Op.Has(fsnotify.Rename) {
+		return true
+	}
+
+	ignored, _ := sc.Validator.IsIgnored(event.Name)
+	return !ignored
+}
+
+// watchDirectories makes the watcher follow exactly the directories of the source tree
+func (sc *SourceCollector) watchDirectories(watcher *fsnotify.Watcher, sourceTree *SourceTree, watched map[string]struct{}) {
+	directories := make(map[string]struct{})
+
+	queue := []*SourceTree{sourceTree}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+
+		if node == nil || node.Nodes == nil {
+			continue
+		}
+
+		directories[node.Root.Path] = struct{}{}
+		queue = append(queue, node.Nodes...)
+	}
+
+	// Stop watching the directories which are gone or ignored now
+	for path := range watched {
+		if _, ok := directories[path]; !ok {
+			watcher.Remove(path)
+			delete(watched, path)
+		}
+	}
+
+	for path := range directories {
+		if _, ok := watched[path]; ok {
+			continue
+		}
+
+		if err := watcher.Add(path); err == nil {
+			watched[path] = struct{}{}
+		}
+	}
+}
+
+// fileVersions returns the version of every file of the source tree written to the output by its relative path.
+// The content comes from the cache filled by the build, so a file which was touched without changing its output has the same version.
+func (sc *SourceCollector) fileVersions(ctx context.Context, sourceTree *SourceTree) map[string]fileVersion {
+	var (
+		mu       sync.Mutex
+		versions = make(map[string]fileVersion)
+	)
+
+	sc.transformSourceFiles(ctx, sourceTree, func(node SourceNode, relPath string, blob *cache.Blob, err error) {
+		if err != nil || blob.Skipped {
+			return
+		}
+
+		version := fileVersion(duplicateHash(&processedFile{data: blob.Data, transformed: blob.Transformed}))
+
+		mu.Lock()
+		versions[relPath] = version
+		mu.Unlock()
+	})
+
+	return versions
+}
+
+// diffVersions compares the file versions of two builds
+func diffVersions(previous map[string]fileVersion, current map[string]fileVersion) (added []string, modified []string, removed []string) {
+	for path, version := range current {
+		previousVersion, ok := previous[path]
+		switch {
+		case !ok:
+			added = append(added, path)
+		case previousVersion != version:
+			modified = append(modified, path)
+		}
+	}
+
+	for path := range previous {
+		if _, ok := current[path]; !ok {
+			removed = append(removed, path)
+		}
+	}
+
+	sort.Strings(added)
+	sort.Strings(modified)
+	sort.Strings(removed)
+
+	return added, modified, removed
+}
@@ -0,0 +1,149 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/hitesh22rana/sourcecollector/pkg/cache"
+	"github.com/hitesh22rana/sourcecollector/pkg/internal/testutil"
+	"github.com/hitesh22rana/sourcecollector/pkg/validators"
+)
+
+func TestDiffVersions(t *testing.T) {
+	previous := map[string]fileVersion{
+		"input/a.go": {1},
+		"input/b.go": {2},
+		"input/c.go": {3},
+	}
+	current := map[string]fileVersion{
+		"input/a.go": {1},
+		"input/b.go": {4},
+		"input/e.go": {5},
+		"input/d.go": {6},
+	}
+
+	added, modified, removed := diffVersions(previous, current)
+	if want := []string{"input/d.go", "input/e.go"}; !reflect.DeepEqual(added, want) {
+		t.Errorf("added %v, want %v", added, want)
+	}
+	if want := []string{"input/b.go"}; !reflect.DeepEqual(modified, want) {
+		t.Errorf("modified %v, want %v", modified, want)
+	}
+	if want := []string{"input/c.go"}; !reflect.DeepEqual(removed, want) {
+		t.Errorf("removed %v, want %v", removed, want)
+	}
+
+	if added, modified, removed := diffVersions(current, current); added != nil || modified != nil || removed != nil {
+		t.Errorf("unchanged build diffed as %v, %v, %v", added, modified, removed)
+	}
+}
+
+func TestIsRelevantEvent(t *testing.T) {
+	sc := newOutputCollector(t, outputFiles)
+
+	var err error
+	sc.Validator, err = validators.NewPatternValidator(testutil.AcceptAll{}, sc.Input, nil, []string{"docs/**"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		event fsnotify.Event
+		want  bool
+	}{
+		{"collected file", fsnotify.Event{Name: filepath.Join(sc.Input, "main.go"), Op: fsnotify.Write}, true},
+		{"new file", fsnotify.Event{Name: filepath.Join(sc.Input, "new.go"), Op: fsnotify.Create}, true},
+		{"output", fsnotify.Event{Name: sc.Output, Op: fsnotify.Write}, false},
+		{"temporary output", fsnotify.Event{Name: filepath.Join(filepath.Dir(sc.Output), ".output.txt.123.tmp"), Op: fsnotify.Create}, false},
+		{"permissions", fsnotify.Event{Name: filepath.Join(sc.Input, "main.go"), Op: fsnotify.Chmod}, false},
+		{"excluded file", fsnotify.Event{Name: filepath.Join(sc.Input, "docs", "guide.md"), Op: fsnotify.Write}, false},
+		{"removed file", fsnotify.Event{Name: filepath.Join(sc.Input, "docs", "guide.md"), Op: fsnotify.Remove}, true},
+		{"renamed file", fsnotify.Event{Name: filepath.Join(sc.Input, "util", "util.go"), Op: fsnotify.Rename}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := sc.isRelevantEvent(test.event); got != test.want {
+				t.Errorf("isRelevantEvent(%v) = %t, want %t", test.event, got, test.want)
+			}
+		})
+	}
+}
+
+func TestFileVersions(t *testing.T) {
+	sc := newOutputCollector(t, map[string]string{
+		"main.go": "package main\n\nfunc main() {\n\tprintln(1)\n}\n",
+		"util.go": "package main\n",
+	})
+	sc.Outline = true
+
+	var err error
+	sc.Cache, err = cache.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	versions := func() map[string]fileVersion {
+		sourceTree, err := sc.build(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sc.fileVersions(ctx, sourceTree)
+	}
+
+	previous := versions()
+
+	// A touched file and a change which does not reach the outline leave the output as it was
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(filepath.Join(sc.Input, "util.go"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(sc.Input, "main.go"), []byte("package main\n\nfunc main() {\n\tprintln(2)\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	current := versions()
+	if added, modified, removed := diffVersions(previous, current); added != nil || modified != nil || removed != nil {
+		t.Errorf("unchanged output diffed as %v, %v, %v", added, modified, removed)
+	}
+
+	// A new declaration changes the outline
+	if err := os.WriteFile(filepath.Join(sc.Input, "util.go"), []byte("package main\n\nfunc util() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, modified, _ := diffVersions(current, versions()); !reflect.DeepEqual(modified, []string{filepath.Join("input", "util.go")}) {
+		t.Errorf("modified %v, want input/util.go", modified)
+	}
+}
+
+func TestNewWatchSummary(t *testing.T) {
+	sc := newOutputCollector(t, outputFiles)
+	collectionErr := newCollectionError([]*FileError{{Path: "input/main.go", Err: os.ErrNotExist}})
+
+	// The output is written without the failed files, so the build succeeded
+	summary := sc.newWatchSummary(collectionErr)
+	if summary.Err != nil || summary.Failed != collectionErr {
+		t.Errorf("skip policy: summary error %v, failed %v", summary.Err, summary.Failed)
+	}
+
+	// The output is kept as it was, so the build failed
+	sc.OnError = ErrorPolicyFail
+	summary = sc.newWatchSummary(collectionErr)
+	if !errors.Is(summary.Err, os.ErrNotExist) || summary.Failed != nil {
+		t.Errorf("fail policy: summary error %v, failed %v", summary.Err, summary.Failed)
+	}
+
+	if summary := sc.newWatchSummary(context.Canceled); summary.Err != context.Canceled || summary.Failed != nil {
+		t.Errorf("cancelled build: summary error %v, failed %v", summary.Err, summary.Failed)
+	}
+}